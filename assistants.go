@@ -0,0 +1,343 @@
+package openai
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Simplou/goxios"
+)
+
+// Types of the tools of an assistant.
+const (
+	AssistantToolFunction        = "function"
+	AssistantToolCodeInterpreter = "code_interpreter"
+	AssistantToolFileSearch      = "file_search"
+)
+
+// assistantsBeta is required by every endpoint of the Assistants API.
+var assistantsBeta = goxios.Header{Key: "OpenAI-Beta", Value: "assistants=v2"}
+
+// betaClient adds a beta header to the requests sent with an OpenAIClient.
+type betaClient struct {
+	OpenAIClient
+	header goxios.Header
+}
+
+func (c betaClient) Headers() []goxios.Header {
+	headers := c.OpenAIClient.Headers()
+	return append(headers[:len(headers):len(headers)], c.header)
+}
+
+func assistantsAPI(api OpenAIClient) OpenAIClient {
+	return betaClient{OpenAIClient: api, header: assistantsBeta}
+}
+
+type (
+	// AssistantTool is a tool of an assistant or a run: a function, as in chat
+	// completions, or a tool hosted by OpenAI.
+	AssistantTool struct {
+		Type       string          `json:"type"`
+		Function   *Function       `json:"function,omitempty"`
+		FileSearch *FileSearchTool `json:"file_search,omitempty"`
+	}
+
+	FileSearchTool struct {
+		// MaxNumResults is between 1 and 50.
+		MaxNumResults  int             `json:"max_num_results,omitempty"`
+		RankingOptions *RankingOptions `json:"ranking_options,omitempty"`
+	}
+
+	RankingOptions struct {
+		Ranker         string  `json:"ranker,omitempty"`
+		ScoreThreshold float64 `json:"score_threshold"`
+	}
+
+	// ToolResources are the files used by the hosted tools.
+	ToolResources struct {
+		CodeInterpreter *CodeInterpreterResources `json:"code_interpreter,omitempty"`
+		FileSearch      *FileSearchResources      `json:"file_search,omitempty"`
+	}
+
+	CodeInterpreterResources struct {
+		FileIds []string `json:"file_ids"`
+	}
+
+	FileSearchResources struct {
+		VectorStoreIds []string `json:"vector_store_ids,omitempty"`
+	}
+
+	// AssistantRequest creates or modifies an assistant. Model is required to
+	// create one; the fields left empty are not modified.
+	AssistantRequest struct {
+		Model          string            `json:"model,omitempty"`
+		Name           string            `json:"name,omitempty"`
+		Description    string            `json:"description,omitempty"`
+		Instructions   string            `json:"instructions,omitempty"`
+		Tools          []AssistantTool   `json:"tools,omitempty"`
+		ToolResources  *ToolResources    `json:"tool_resources,omitempty"`
+		Metadata       map[string]string `json:"metadata,omitempty"`
+		Temperature    *float64          `json:"temperature,omitempty"`
+		TopP           *float64          `json:"top_p,omitempty"`
+		ResponseFormat any               `json:"response_format,omitempty"`
+	}
+
+	// Assistant is an assistant. Times are Unix timestamps in seconds.
+	Assistant struct {
+		Id             string            `json:"id"`
+		Object         string            `json:"object"`
+		CreatedAt      int64             `json:"created_at"`
+		Name           string            `json:"name,omitempty"`
+		Description    string            `json:"description,omitempty"`
+		Model          string            `json:"model"`
+		Instructions   string            `json:"instructions,omitempty"`
+		Tools          []AssistantTool   `json:"tools"`
+		ToolResources  *ToolResources    `json:"tool_resources,omitempty"`
+		Metadata       map[string]string `json:"metadata,omitempty"`
+		Temperature    *float64          `json:"temperature,omitempty"`
+		TopP           *float64          `json:"top_p,omitempty"`
+		ResponseFormat any               `json:"response_format,omitempty"`
+	}
+
+	CreateThreadRequest struct {
+		Messages      []CreateMessageRequest `json:"messages,omitempty"`
+		ToolResources *ToolResources         `json:"tool_resources,omitempty"`
+		Metadata      map[string]string      `json:"metadata,omitempty"`
+	}
+
+	ModifyThreadRequest struct {
+		ToolResources *ToolResources    `json:"tool_resources,omitempty"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+	}
+
+	// Thread is a conversation between a user and assistants.
+	Thread struct {
+		Id            string            `json:"id"`
+		Object        string            `json:"object"`
+		CreatedAt     int64             `json:"created_at"`
+		ToolResources *ToolResources    `json:"tool_resources,omitempty"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+	}
+
+	// CreateMessageRequest adds a message to a thread. Content is a string or
+	// a list of content parts, such as []MessageContent.
+	CreateMessageRequest struct {
+		Role        string            `json:"role"`
+		Content     any               `json:"content"`
+		Attachments []Attachment      `json:"attachments,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+	}
+
+	// Attachment is a file made available to the tools of a message.
+	Attachment struct {
+		FileId string          `json:"file_id"`
+		Tools  []AssistantTool `json:"tools"`
+	}
+
+	// ThreadMessage is a message of a thread.
+	ThreadMessage struct {
+		Id          string            `json:"id"`
+		Object      string            `json:"object"`
+		CreatedAt   int64             `json:"created_at"`
+		ThreadId    string            `json:"thread_id"`
+		Status      string            `json:"status,omitempty"`
+		Role        string            `json:"role"`
+		Content     []MessageContent  `json:"content"`
+		AssistantId string            `json:"assistant_id,omitempty"`
+		RunId       string            `json:"run_id,omitempty"`
+		Attachments []Attachment      `json:"attachments,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+	}
+
+	// MessageContent is a part of a message: text, an uploaded image file or
+	// an image URL, depending on Type.
+	MessageContent struct {
+		Type      string            `json:"type"`
+		Text      *MessageText      `json:"text,omitempty"`
+		ImageFile *MessageImageFile `json:"image_file,omitempty"`
+		ImageURL  *MessageImageURL  `json:"image_url,omitempty"`
+	}
+
+	MessageText struct {
+		Value       string              `json:"value"`
+		Annotations []MessageAnnotation `json:"annotations,omitempty"`
+	}
+
+	// MessageAnnotation points to the file cited or generated by Text.
+	MessageAnnotation struct {
+		Type         string `json:"type"`
+		Text         string `json:"text"`
+		StartIndex   int    `json:"start_index"`
+		EndIndex     int    `json:"end_index"`
+		FileCitation *struct {
+			FileId string `json:"file_id"`
+		} `json:"file_citation,omitempty"`
+		FilePath *struct {
+			FileId string `json:"file_id"`
+		} `json:"file_path,omitempty"`
+	}
+
+	MessageImageFile struct {
+		FileId string `json:"file_id"`
+		Detail string `json:"detail,omitempty"`
+	}
+
+	MessageImageURL struct {
+		URL    string `json:"url"`
+		Detail string `json:"detail,omitempty"`
+	}
+
+	// ListMessagesParams filters and paginates ListMessages.
+	ListMessagesParams struct {
+		ListParams
+		// RunId only lists the messages created by a run.
+		RunId string
+	}
+
+	// ModifyRequest updates the metadata of a resource.
+	ModifyRequest struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+)
+
+// FunctionTools converts chat completion tools to assistant tools.
+func FunctionTools(tools ...Tool) []AssistantTool {
+	assistantTools := make([]AssistantTool, len(tools))
+	for i, tool := range tools {
+		function := tool.Function
+		assistantTools[i] = AssistantTool{Type: AssistantToolFunction, Function: &function}
+	}
+	return assistantTools
+}
+
+// Text returns the text parts of the message.
+func (m *ThreadMessage) Text() string {
+	var text strings.Builder
+	for _, content := range m.Content {
+		if content.Text != nil {
+			text.WriteString(content.Text.Value)
+		}
+	}
+	return text.String()
+}
+
+func (p *ListMessagesParams) queryParams() []goxios.QueryParam {
+	if p == nil {
+		return nil
+	}
+	params := p.ListParams.queryParams()
+	if p.RunId != "" {
+		params = append(params, goxios.QueryParam{Key: "run_id", Value: p.RunId})
+	}
+	return params
+}
+
+// postAssistants sends body to an endpoint of the Assistants API and decodes the response.
+func postAssistants[T any](api OpenAIClient, httpClient HTTPClient, path string, body any) (*T, *OpenAIErr) {
+	res, err := postJSON(assistantsAPI(api), httpClient, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](res)
+}
+
+func CreateAssistant(api OpenAIClient, httpClient HTTPClient, body *AssistantRequest) (*Assistant, *OpenAIErr) {
+	if body.Model == "" {
+		return nil, errInvalidRequest(errors.New("model is required"))
+	}
+	return postAssistants[Assistant](api, httpClient, "/assistants", body)
+}
+
+func ModifyAssistant(api OpenAIClient, httpClient HTTPClient, assistantId string, body *AssistantRequest) (*Assistant, *OpenAIErr) {
+	if assistantId == "" {
+		return nil, errInvalidRequest(errors.New("assistant id is required"))
+	}
+	return postAssistants[Assistant](api, httpClient, "/assistants"+pathID(assistantId), body)
+}
+
+func ListAssistants(api OpenAIClient, httpClient HTTPClient, params *ListParams) (*List[Assistant], *OpenAIErr) {
+	return getJSON[List[Assistant]](assistantsAPI(api), httpClient, "/assistants", params.queryParams()...)
+}
+
+func RetrieveAssistant(api OpenAIClient, httpClient HTTPClient, assistantId string) (*Assistant, *OpenAIErr) {
+	if assistantId == "" {
+		return nil, errInvalidRequest(errors.New("assistant id is required"))
+	}
+	return getJSON[Assistant](assistantsAPI(api), httpClient, "/assistants"+pathID(assistantId))
+}
+
+func DeleteAssistant(api OpenAIClient, httpClient HTTPDeleteClient, assistantId string) (*Deletion, *OpenAIErr) {
+	if assistantId == "" {
+		return nil, errInvalidRequest(errors.New("assistant id is required"))
+	}
+	return deleteJSON[Deletion](assistantsAPI(api), httpClient, "/assistants"+pathID(assistantId))
+}
+
+// CreateThread creates a thread, with its first messages when body sets them.
+func CreateThread(api OpenAIClient, httpClient HTTPClient, body *CreateThreadRequest) (*Thread, *OpenAIErr) {
+	if body == nil {
+		body = &CreateThreadRequest{}
+	}
+	return postAssistants[Thread](api, httpClient, "/threads", body)
+}
+
+func ModifyThread(api OpenAIClient, httpClient HTTPClient, threadId string, body *ModifyThreadRequest) (*Thread, *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	return postAssistants[Thread](api, httpClient, "/threads"+pathID(threadId), body)
+}
+
+func RetrieveThread(api OpenAIClient, httpClient HTTPClient, threadId string) (*Thread, *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	return getJSON[Thread](assistantsAPI(api), httpClient, "/threads"+pathID(threadId))
+}
+
+func DeleteThread(api OpenAIClient, httpClient HTTPDeleteClient, threadId string) (*Deletion, *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	return deleteJSON[Deletion](assistantsAPI(api), httpClient, "/threads"+pathID(threadId))
+}
+
+func CreateMessage(api OpenAIClient, httpClient HTTPClient, threadId string, body *CreateMessageRequest) (*ThreadMessage, *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	if body.Role != "user" && body.Role != "assistant" {
+		return nil, errInvalidRequest(errors.New("role must be user or assistant"))
+	}
+	return postAssistants[ThreadMessage](api, httpClient, "/threads"+pathID(threadId)+"/messages", body)
+}
+
+// ListMessages returns a page of the messages of a thread, newest first unless
+// params.Order is asc.
+func ListMessages(api OpenAIClient, httpClient HTTPClient, threadId string, params *ListMessagesParams) (*List[ThreadMessage], *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	return getJSON[List[ThreadMessage]](assistantsAPI(api), httpClient, "/threads"+pathID(threadId)+"/messages", params.queryParams()...)
+}
+
+func RetrieveMessage(api OpenAIClient, httpClient HTTPClient, threadId, messageId string) (*ThreadMessage, *OpenAIErr) {
+	if threadId == "" || messageId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and message id are required"))
+	}
+	return getJSON[ThreadMessage](assistantsAPI(api), httpClient, "/threads"+pathID(threadId)+"/messages"+pathID(messageId))
+}
+
+// ModifyMessage replaces the metadata of a message.
+func ModifyMessage(api OpenAIClient, httpClient HTTPClient, threadId, messageId string, metadata map[string]string) (*ThreadMessage, *OpenAIErr) {
+	if threadId == "" || messageId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and message id are required"))
+	}
+	return postAssistants[ThreadMessage](api, httpClient, "/threads"+pathID(threadId)+"/messages"+pathID(messageId), &ModifyRequest{Metadata: metadata})
+}
+
+func DeleteMessage(api OpenAIClient, httpClient HTTPDeleteClient, threadId, messageId string) (*Deletion, *OpenAIErr) {
+	if threadId == "" || messageId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and message id are required"))
+	}
+	return deleteJSON[Deletion](assistantsAPI(api), httpClient, "/threads"+pathID(threadId)+"/messages"+pathID(messageId))
+}
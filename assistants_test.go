@@ -0,0 +1,179 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockAssistantsHTTPClient runs one thread whose run calls get_weather twice,
+// then completes once the outputs are submitted.
+type MockAssistantsHTTPClient struct {
+	requests []string
+	outputs  []ToolOutput
+	run      Run
+	polls    int
+}
+
+func (c *MockAssistantsHTTPClient) record(method, url string, opts *goxios.RequestOpts) (string, error) {
+	beta := false
+	for _, header := range opts.Headers {
+		beta = beta || header == assistantsBeta
+	}
+	if !beta {
+		return "", errors.New("missing OpenAI-Beta header")
+	}
+	path := strings.TrimPrefix(url, "https://fake.api.openai.com/v1")
+	c.requests = append(c.requests, method+" "+path)
+	return path, nil
+}
+
+func (c *MockAssistantsHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	path, err := c.record(http.MethodPost, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case path == "/assistants":
+		var body AssistantRequest
+		if err := json.Unmarshal(b, &body); err != nil {
+			return nil, err
+		}
+		return c.json(Assistant{Id: "asst_1", Object: "assistant", Model: body.Model, Tools: body.Tools})
+	case path == "/threads":
+		return c.json(Thread{Id: "thread_1", Object: "thread"})
+	case path == "/threads/thread_1/messages":
+		return c.json(ThreadMessage{Id: "msg_1", Object: "thread.message", ThreadId: "thread_1", Role: "user"})
+	case path == "/threads/thread_1/runs":
+		c.run = Run{Id: "run_1", Object: "thread.run", ThreadId: "thread_1", AssistantId: "asst_1", Status: RunStatusQueued}
+		return c.json(c.run)
+	case path == "/threads/thread_1/runs/run_1/submit_tool_outputs":
+		var body SubmitToolOutputsRequest
+		if err := json.Unmarshal(b, &body); err != nil {
+			return nil, err
+		}
+		c.outputs = body.ToolOutputs
+		c.run.Status, c.run.RequiredAction = RunStatusInProgress, nil
+		return c.json(c.run)
+	}
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func (c *MockAssistantsHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	path, err := c.record(http.MethodGet, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	switch path {
+	case "/threads/thread_1/runs/run_1":
+		c.polls++
+		switch {
+		case c.run.Status == RunStatusQueued:
+			c.run.Status = RunStatusRequiresAction
+			c.run.RequiredAction = &RequiredAction{Type: "submit_tool_outputs"}
+			for i, city := range []string{"Lisbon", "Atlantis"} {
+				c.run.RequiredAction.SubmitToolOutputs.ToolCalls = append(c.run.RequiredAction.SubmitToolOutputs.ToolCalls, ToolCall{
+					Id: fmt.Sprintf("call_%d", i+1), Type: "function", Function: FunctionCall{Name: "get_weather", Args: fmt.Sprintf(`{"city":%q}`, city)},
+				})
+			}
+		case c.run.Status == RunStatusInProgress:
+			c.run.Status = RunStatusCompleted
+		}
+		return c.json(c.run)
+	case "/threads/thread_1/messages":
+		return jsonResponse(http.StatusOK, `{"object":"list","data":[{"id":"msg_2","object":"thread.message","thread_id":"thread_1","role":"assistant","content":[{"type":"text","text":{"value":"It is 21°C in Lisbon.","annotations":[]}}]}],"has_more":false}`), nil
+	}
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func (c *MockAssistantsHTTPClient) json(v any) (*http.Response, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(http.StatusOK, string(b)), nil
+}
+
+func TestWaitRun(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockAssistantsHTTPClient{}
+	weather := Tool{Type: "function", Function: Function{Name: "get_weather", Description: "Current weather of a city"}}
+	assistant, err := CreateAssistant(api, httpClient, &AssistantRequest{Model: "gpt-4o-mini", Tools: append(FunctionTools(weather), AssistantTool{Type: AssistantToolCodeInterpreter})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assistant.Tools) != 2 || assistant.Tools[0].Function.Name != "get_weather" || assistant.Tools[1].Function != nil {
+		t.Errorf("unexpected tools %+v", assistant.Tools)
+	}
+	thread, err := CreateThread(api, httpClient, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := CreateMessage(api, httpClient, thread.Id, &CreateMessageRequest{Role: "user", Content: "Weather in Lisbon and Atlantis?"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	run, err := CreateRun(api, httpClient, thread.Id, &CreateRunRequest{AssistantId: assistant.Id})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handlers := map[string]ToolHandler{
+		"get_weather": func(ctx context.Context, arguments string) (string, error) {
+			var args struct{ City string }
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", err
+			}
+			if args.City == "Atlantis" {
+				return "", errors.New("unknown city")
+			}
+			return `{"celsius":21}`, nil
+		},
+	}
+	run, err = WaitRun(context.Background(), api, httpClient, thread.Id, run.Id, &RunPollOpts{Interval: time.Millisecond, Handlers: handlers})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.Status != RunStatusCompleted || httpClient.polls != 2 {
+		t.Errorf("expected the run to complete after 2 polls, got %s after %d", run.Status, httpClient.polls)
+	}
+	want := []ToolOutput{{ToolCallId: "call_1", Output: `{"celsius":21}`}, {ToolCallId: "call_2", Output: "error: unknown city"}}
+	if fmt.Sprint(httpClient.outputs) != fmt.Sprint(want) {
+		t.Errorf("expected outputs %v, got %v", want, httpClient.outputs)
+	}
+
+	messages, err := ListMessages(api, httpClient, thread.Id, nil)
+	if err != nil || len(messages.Data) != 1 || messages.Data[0].Text() != "It is 21°C in Lisbon." {
+		t.Errorf("unexpected messages %+v, error %v", messages, err)
+	}
+}
+
+func TestWaitRunMissingHandler(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockAssistantsHTTPClient{}
+	run, err := CreateRun(api, httpClient, "thread_1", &CreateRunRequest{AssistantId: "asst_1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = WaitRun(context.Background(), api, httpClient, "thread_1", run.Id, &RunPollOpts{Interval: time.Millisecond})
+	if err == nil || err.Err.Type != "tool_handler_missing" {
+		t.Errorf("expected a missing handler error, got %v", err)
+	}
+	if httpClient.outputs != nil {
+		t.Errorf("expected no outputs submitted, got %v", httpClient.outputs)
+	}
+	if _, err := CreateMessage(api, httpClient, "thread_1", &CreateMessageRequest{Role: "system", Content: "Hi"}); err == nil {
+		t.Error("expected an error for a system message")
+	}
+}
@@ -0,0 +1,401 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Endpoints that can be called from a batch.
+const (
+	BatchEndpointChatCompletions = "/v1/chat/completions"
+	BatchEndpointEmbeddings      = "/v1/embeddings"
+)
+
+// Limits of one batch input file.
+const (
+	MaxBatchRequests = 50000
+	MaxBatchFileSize = 200 << 20
+)
+
+// Batch statuses.
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// DefaultBatchCompletionWindow is the only completion window supported by the API.
+const DefaultBatchCompletionWindow = "24h"
+
+// ErrBatchFull is returned by BatchBuilder.Add when the request would exceed
+// MaxBatchRequests or MaxBatchFileSize.
+var ErrBatchFull = errors.New("batch is full")
+
+type (
+	// BatchRequest is one line of a batch input file.
+	BatchRequest[Body any] struct {
+		CustomId string `json:"custom_id"`
+		Method   string `json:"method"`
+		Url      string `json:"url"`
+		Body     Body   `json:"body"`
+	}
+
+	CreateBatchRequest struct {
+		InputFileId string `json:"input_file_id"`
+		// Endpoint is one of the BatchEndpoint constants.
+		Endpoint string `json:"endpoint"`
+		// CompletionWindow defaults to DefaultBatchCompletionWindow.
+		CompletionWindow string            `json:"completion_window"`
+		Metadata         map[string]string `json:"metadata,omitempty"`
+	}
+
+	// Batch is a batch job. Times are Unix timestamps in seconds.
+	Batch struct {
+		Id               string             `json:"id"`
+		Object           string             `json:"object"`
+		Endpoint         string             `json:"endpoint"`
+		Errors           *List[BatchError]  `json:"errors,omitempty"`
+		InputFileId      string             `json:"input_file_id"`
+		CompletionWindow string             `json:"completion_window"`
+		Status           string             `json:"status"`
+		OutputFileId     string             `json:"output_file_id,omitempty"`
+		ErrorFileId      string             `json:"error_file_id,omitempty"`
+		CreatedAt        int64              `json:"created_at"`
+		InProgressAt     int64              `json:"in_progress_at,omitempty"`
+		ExpiresAt        int64              `json:"expires_at,omitempty"`
+		FinalizingAt     int64              `json:"finalizing_at,omitempty"`
+		CompletedAt      int64              `json:"completed_at,omitempty"`
+		FailedAt         int64              `json:"failed_at,omitempty"`
+		ExpiredAt        int64              `json:"expired_at,omitempty"`
+		CancellingAt     int64              `json:"cancelling_at,omitempty"`
+		CancelledAt      int64              `json:"cancelled_at,omitempty"`
+		RequestCounts    BatchRequestCounts `json:"request_counts"`
+		Metadata         map[string]string  `json:"metadata,omitempty"`
+	}
+
+	// BatchError is an error of the input file found while validating a batch.
+	BatchError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Param   string `json:"param,omitempty"`
+		Line    int    `json:"line,omitempty"`
+	}
+
+	BatchRequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	}
+)
+
+// Done reports whether the batch reached a final status.
+func (b *Batch) Done() bool {
+	switch b.Status {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// BatchAdder is implemented by the types that collect batch requests,
+// BatchBuilder and BatchOrchestrator.
+type BatchAdder interface {
+	Add(customId, endpoint string, body any) error
+}
+
+// BatchBuilder builds a batch input file in memory. All the requests of a batch
+// must target the same endpoint and have unique custom ids.
+type BatchBuilder struct {
+	endpoint string
+	lines    [][]byte
+	ids      []string
+	seen     map[string]bool
+	size     int
+	// maxRequests and maxSize lower the limits of the batch.
+	maxRequests, maxSize int
+}
+
+func NewBatchBuilder() *BatchBuilder {
+	return &BatchBuilder{seen: map[string]bool{}, maxRequests: MaxBatchRequests, maxSize: MaxBatchFileSize}
+}
+
+// Add appends a POST request to endpoint with body. It returns ErrBatchFull,
+// leaving the builder unchanged, when the batch limits would be exceeded.
+func (b *BatchBuilder) Add(customId, endpoint string, body any) error {
+	if customId == "" {
+		return errors.New("custom_id is required")
+	}
+	if b.seen[customId] {
+		return fmt.Errorf("duplicate custom_id %q", customId)
+	}
+	if b.endpoint != "" && endpoint != b.endpoint {
+		return fmt.Errorf("all requests of a batch must target %s, got %s", b.endpoint, endpoint)
+	}
+	line, err := json.Marshal(BatchRequest[any]{CustomId: customId, Method: http.MethodPost, Url: endpoint, Body: body})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if len(b.lines)+1 > b.maxRequests || b.size+len(line) > b.maxSize {
+		return ErrBatchFull
+	}
+	b.endpoint = endpoint
+	b.lines = append(b.lines, line)
+	b.ids = append(b.ids, customId)
+	b.seen[customId] = true
+	b.size += len(line)
+	return nil
+}
+
+// AddChatCompletion adds a chat completion request to b.
+func AddChatCompletion[Messages any](b BatchAdder, customId string, body *CompletionRequest[Messages]) error {
+	if body.Model == "" {
+		request := *body
+		request.Model = DefaultChatModel
+		body = &request
+	}
+	if err := validateChatModel(body.Model, body.Messages, body.Tools); err != nil {
+		return err
+	}
+	return b.Add(customId, BatchEndpointChatCompletions, body)
+}
+
+// AddEmbedding adds an embedding request to b.
+func AddEmbedding[Input string | []string](b BatchAdder, customId string, body *EmbeddingRequest[Input]) error {
+	if body.Model == "" {
+		request := *body
+		request.Model = DefaultEmbeddingModel
+		body = &request
+	}
+	if err := validateEmbeddingModel(body.Model, body.Dimensions); err != nil {
+		return err
+	}
+	return b.Add(customId, BatchEndpointEmbeddings, body)
+}
+
+// Len returns the number of requests in the batch.
+func (b *BatchBuilder) Len() int {
+	return len(b.lines)
+}
+
+// Size returns the size of the input file in bytes.
+func (b *BatchBuilder) Size() int {
+	return b.size
+}
+
+// Endpoint returns the endpoint targeted by the requests.
+func (b *BatchBuilder) Endpoint() string {
+	return b.endpoint
+}
+
+// CustomIds returns the custom ids of the requests, in order.
+func (b *BatchBuilder) CustomIds() []string {
+	return append([]string(nil), b.ids...)
+}
+
+// WriteTo writes the input file in JSONL to w.
+func (b *BatchBuilder) WriteTo(w io.Writer) (int64, error) {
+	return b.reader().WriteTo(w)
+}
+
+func (b *BatchBuilder) reader() *bytes.Buffer {
+	return bytes.NewBuffer(bytes.Join(b.lines, nil))
+}
+
+// CreateBatch creates a batch from an uploaded input file.
+func CreateBatch(api OpenAIClient, httpClient HTTPClient, body *CreateBatchRequest) (*Batch, *OpenAIErr) {
+	if body.InputFileId == "" || body.Endpoint == "" {
+		return nil, errInvalidRequest(errors.New("input_file_id and endpoint are required"))
+	}
+	request := *body
+	if request.CompletionWindow == "" {
+		request.CompletionWindow = DefaultBatchCompletionWindow
+	}
+	res, err := postJSON(api, httpClient, "/batches", &request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[Batch](res)
+}
+
+// SubmitBatch uploads the input file built by b and creates a batch from it.
+func SubmitBatch(api OpenAIClient, httpClient HTTPClient, b *BatchBuilder, metadata map[string]string) (*Batch, *OpenAIErr) {
+	if b.Len() == 0 {
+		return nil, errInvalidRequest(errors.New("batch is empty"))
+	}
+	file, err := UploadFile(api, httpClient, &UploadFileRequest{
+		Purpose:     FilePurposeBatch,
+		Filename:    "batch.jsonl",
+		File:        b.reader(),
+		ContentType: "application/jsonl",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return CreateBatch(api, httpClient, &CreateBatchRequest{InputFileId: file.Id, Endpoint: b.Endpoint(), Metadata: metadata})
+}
+
+func RetrieveBatch(api OpenAIClient, httpClient HTTPClient, batchId string) (*Batch, *OpenAIErr) {
+	if batchId == "" {
+		return nil, errInvalidRequest(errors.New("batch id is required"))
+	}
+	return getJSON[Batch](api, httpClient, "/batches"+pathID(batchId))
+}
+
+// CancelBatch cancels a batch in progress. The batch is cancelling until the
+// requests already running finish.
+func CancelBatch(api OpenAIClient, httpClient HTTPClient, batchId string) (*Batch, *OpenAIErr) {
+	if batchId == "" {
+		return nil, errInvalidRequest(errors.New("batch id is required"))
+	}
+	res, err := postJSON(api, httpClient, "/batches"+pathID(batchId)+"/cancel", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[Batch](res)
+}
+
+func ListBatches(api OpenAIClient, httpClient HTTPClient, params *ListParams) (*List[Batch], *OpenAIErr) {
+	return getJSON[List[Batch]](api, httpClient, "/batches", params.queryParams()...)
+}
+
+// BatchPollOpts configures WaitBatch. Zero values use the defaults.
+type BatchPollOpts struct {
+	// Interval is the first delay between two polls, 5s by default. It doubles
+	// after every poll up to MaxInterval, 1min by default.
+	Interval, MaxInterval time.Duration
+	// MaxRetries bounds the consecutive failed polls, 5 by default. Polls failing
+	// with a rate limit or server error are retried, other errors are returned.
+	MaxRetries int
+	// OnPoll, when set, is called with every batch status received.
+	OnPoll func(*Batch)
+}
+
+// WaitBatch polls a batch with exponential backoff until it reaches a final
+// status, and returns it. Canceling ctx stops waiting, not the batch.
+func WaitBatch(ctx context.Context, api OpenAIClient, httpClient HTTPClient, batchId string, opts *BatchPollOpts) (*Batch, *OpenAIErr) {
+	if opts == nil {
+		opts = &BatchPollOpts{}
+	}
+	fetch := func() (*Batch, *OpenAIErr) {
+		return RetrieveBatch(api, httpClient, batchId)
+	}
+	return poll(ctx, opts.Interval, opts.MaxInterval, opts.MaxRetries, fetch, func(batch *Batch) bool {
+		if opts.OnPoll != nil {
+			opts.OnPoll(batch)
+		}
+		return batch.Done()
+	})
+}
+
+type (
+	// BatchResult is one line of a batch output or error file. Either Response
+	// or Error is set.
+	BatchResult[T any] struct {
+		Id       string            `json:"id"`
+		CustomId string            `json:"custom_id"`
+		Response *BatchResponse[T] `json:"response"`
+		Error    *BatchError       `json:"error"`
+	}
+
+	// BatchResponse is the response to one request of a batch. Body is set when
+	// the request succeeded and Err when it failed.
+	BatchResponse[T any] struct {
+		StatusCode int
+		RequestId  string
+		Body       *T
+		Err        *OpenAIErr
+	}
+)
+
+func (r *BatchResponse[T]) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		StatusCode int             `json:"status_code"`
+		RequestId  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	r.StatusCode, r.RequestId = raw.StatusCode, raw.RequestId
+	if raw.StatusCode >= http.StatusBadRequest {
+		r.Err = &OpenAIErr{status: raw.StatusCode}
+		return json.Unmarshal(raw.Body, r.Err)
+	}
+	r.Body = new(T)
+	return json.Unmarshal(raw.Body, r.Body)
+}
+
+// MarshalJSON encodes the response as in batch output files.
+func (r BatchResponse[T]) MarshalJSON() ([]byte, error) {
+	line := struct {
+		StatusCode int    `json:"status_code"`
+		RequestId  string `json:"request_id"`
+		Body       any    `json:"body"`
+	}{r.StatusCode, r.RequestId, r.Body}
+	if r.Err != nil {
+		line.Body = r.Err
+	}
+	return json.Marshal(line)
+}
+
+// Err returns the error of the request, or nil when it succeeded.
+func (r *BatchResult[T]) Err() *OpenAIErr {
+	switch {
+	case r.Error != nil:
+		return &OpenAIErr{Err: JSONErr{Message: r.Error.Message, Type: "batch_error", Code: r.Error.Code}}
+	case r.Response == nil:
+		return &OpenAIErr{Err: JSONErr{Message: "no response", Type: "batch_error"}}
+	default:
+		return r.Response.Err
+	}
+}
+
+// DecodeBatchResults decodes a batch output or error file into results keyed by custom id.
+func DecodeBatchResults[T any](r io.Reader, results map[string]*BatchResult[T]) error {
+	decoder := json.NewDecoder(r)
+	for {
+		result := new(BatchResult[T])
+		if err := decoder.Decode(result); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		results[result.CustomId] = result
+	}
+}
+
+// BatchResults downloads the output and error files of a finished batch and
+// decodes them into results keyed by custom id. T is the response of the batch
+// endpoint, such as CompletionResponse or EmbeddingResponse[[]float64].
+// Requests that never ran, for instance in an expired batch, have no result.
+func BatchResults[T any](api OpenAIClient, httpClient HTTPClient, batch *Batch) (map[string]*BatchResult[T], *OpenAIErr) {
+	results := map[string]*BatchResult[T]{}
+	for _, fileId := range []string{batch.OutputFileId, batch.ErrorFileId} {
+		if fileId == "" {
+			continue
+		}
+		content, err := FileContent(api, httpClient, fileId)
+		if err != nil {
+			return nil, err
+		}
+		decodeErr := DecodeBatchResults(content, results)
+		content.Close()
+		if decodeErr != nil {
+			return nil, errCannotDecodeJSON(decodeErr)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,430 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultBatchMaxAttempts is the number of batches a request is sent in before
+// it is given up on, when BatchOrchestratorOpts.MaxAttempts is not set.
+const DefaultBatchMaxAttempts = 3
+
+// BatchOrchestratorOpts configures a BatchOrchestrator. Zero values use the defaults.
+type BatchOrchestratorOpts struct {
+	// MaxRequests and MaxFileSize split the requests across several batches.
+	// They default to, and cannot exceed, MaxBatchRequests and MaxBatchFileSize.
+	MaxRequests, MaxFileSize int
+	// MaxAttempts bounds how many times a failed or expired request is resubmitted.
+	MaxAttempts int
+	// Metadata is attached to every batch.
+	Metadata map[string]string
+	Poll     *BatchPollOpts
+}
+
+// BatchOrchestrator runs any number of requests through the batch API. Requests
+// are split into batches within the API limits, and the ones that fail or expire
+// are resubmitted in new batches. Its state is saved to local files as it runs,
+// so a process that crashed resumes where it stopped: build the same
+// orchestrator, add the same requests, which is a no-op for the requests already
+// known, and call RunBatches again. A crash between creating a batch and saving
+// the state loses track of that batch, and its requests are sent again.
+//
+// A BatchOrchestrator is not safe for concurrent use.
+type BatchOrchestrator struct {
+	path  string
+	opts  BatchOrchestratorOpts
+	state *batchState
+	// saved is the number of requests of state.Order in the requests file.
+	saved int
+}
+
+type (
+	// batchState is the state rebuilt from the requests file and the events of
+	// the state file.
+	batchState struct {
+		// Order lists the custom ids in the order they were added.
+		Order    []string
+		Requests map[string]*batchRequest
+		Batches  []*batchRun
+	}
+
+	batchRequest struct {
+		Endpoint string
+		Body     json.RawMessage
+		Attempts int
+		// Batch is the id of the batch the request is running in.
+		Batch string
+		// Result is the last result line received for the request. It is final
+		// once the request succeeded or ran out of attempts.
+		Result json.RawMessage
+		Done   bool
+	}
+
+	// batchRun is a batch submitted by the orchestrator.
+	batchRun struct {
+		Id        string
+		CustomIds []string
+		Collected bool
+	}
+
+	// batchInput is a line of the requests file, written once per request.
+	batchInput struct {
+		CustomId string          `json:"custom_id"`
+		Endpoint string          `json:"endpoint"`
+		Body     json.RawMessage `json:"body"`
+	}
+
+	// batchEvent is a line of the state file. A submitted batch lists its
+	// CustomIds; a collected batch, or requests that can never be sent, have
+	// Results.
+	batchEvent struct {
+		Batch     string                   `json:"batch,omitempty"`
+		CustomIds []string                 `json:"custom_ids,omitempty"`
+		Results   map[string]*batchOutcome `json:"results,omitempty"`
+	}
+
+	batchOutcome struct {
+		Result   json.RawMessage `json:"result"`
+		Attempts int             `json:"attempts"`
+		Done     bool            `json:"done"`
+	}
+)
+
+// NewBatchOrchestrator returns an orchestrator saving its state to path, and
+// the requests it runs to path with a ".requests" suffix. The state saved by a
+// previous run is loaded when these files exist.
+func NewBatchOrchestrator(path string, opts *BatchOrchestratorOpts) (*BatchOrchestrator, error) {
+	o := &BatchOrchestrator{path: path, state: &batchState{Requests: map[string]*batchRequest{}}}
+	if opts != nil {
+		o.opts = *opts
+	}
+	if o.opts.MaxRequests <= 0 || o.opts.MaxRequests > MaxBatchRequests {
+		o.opts.MaxRequests = MaxBatchRequests
+	}
+	if o.opts.MaxFileSize <= 0 || o.opts.MaxFileSize > MaxBatchFileSize {
+		o.opts.MaxFileSize = MaxBatchFileSize
+	}
+	if o.opts.MaxAttempts <= 0 {
+		o.opts.MaxAttempts = DefaultBatchMaxAttempts
+	}
+	err := readLines(o.requestsPath(), func(line []byte) error {
+		var input batchInput
+		if err := json.Unmarshal(line, &input); err != nil {
+			return err
+		}
+		if _, ok := o.state.Requests[input.CustomId]; !ok {
+			o.state.Order = append(o.state.Order, input.CustomId)
+			o.state.Requests[input.CustomId] = &batchRequest{Endpoint: input.Endpoint, Body: input.Body}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	o.saved = len(o.state.Order)
+	if err := readLines(path, func(line []byte) error {
+		var event batchEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		return o.apply(&event)
+	}); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *BatchOrchestrator) requestsPath() string {
+	return o.path + ".requests"
+}
+
+// readLines calls fn with every line of the file at path, which may not exist.
+// A last line without a newline was cut by a crash while it was written: it is
+// ignored and truncated, so the next line appended starts on its own.
+func readLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var size int64
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return os.Truncate(path, size)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: invalid batch state: %w", path, n, err)
+		}
+		size += int64(len(line))
+	}
+}
+
+// apply updates the state with an event.
+func (o *BatchOrchestrator) apply(event *batchEvent) error {
+	for _, id := range event.CustomIds {
+		request, ok := o.state.Requests[id]
+		if !ok {
+			return fmt.Errorf("unknown custom_id %q", id)
+		}
+		request.Batch = event.Batch
+	}
+	if len(event.CustomIds) > 0 {
+		o.state.Batches = append(o.state.Batches, &batchRun{Id: event.Batch, CustomIds: event.CustomIds})
+	}
+	for id, outcome := range event.Results {
+		request, ok := o.state.Requests[id]
+		if !ok {
+			return fmt.Errorf("unknown custom_id %q", id)
+		}
+		request.Batch = ""
+		request.Result, request.Attempts, request.Done = outcome.Result, outcome.Attempts, outcome.Done
+	}
+	if len(event.Results) > 0 && event.Batch != "" {
+		for _, run := range o.state.Batches {
+			if run.Id == event.Batch {
+				run.Collected = true
+			}
+		}
+	}
+	return nil
+}
+
+// Add adds a POST request to endpoint with body. Requests whose custom id is
+// already known, for instance from a previous run, are ignored.
+func (o *BatchOrchestrator) Add(customId, endpoint string, body any) error {
+	if customId == "" {
+		return errors.New("custom_id is required")
+	}
+	if _, ok := o.state.Requests[customId]; ok {
+		return nil
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	o.state.Order = append(o.state.Order, customId)
+	o.state.Requests[customId] = &batchRequest{Endpoint: endpoint, Body: b}
+	return nil
+}
+
+// Progress counts the requests that succeeded, that failed after their last
+// attempt, and that are still pending.
+func (o *BatchOrchestrator) Progress() (succeeded, failed, pending int) {
+	for _, request := range o.state.Requests {
+		switch {
+		case !request.Done:
+			pending++
+		case request.succeeded():
+			succeeded++
+		default:
+			failed++
+		}
+	}
+	return succeeded, failed, pending
+}
+
+// saveRequests appends the requests added since the last call to the requests
+// file, so every request body is written once.
+func (o *BatchOrchestrator) saveRequests() *OpenAIErr {
+	if o.saved == len(o.state.Order) {
+		return nil
+	}
+	var b []byte
+	for _, id := range o.state.Order[o.saved:] {
+		request := o.state.Requests[id]
+		line, err := json.Marshal(batchInput{CustomId: id, Endpoint: request.Endpoint, Body: request.Body})
+		if err != nil {
+			return errCannotMarshalJSON(err)
+		}
+		b = append(append(b, line...), '\n')
+	}
+	if err := appendFile(o.requestsPath(), b); err != nil {
+		return errCannotSaveState(err)
+	}
+	o.saved = len(o.state.Order)
+	return nil
+}
+
+// save applies event to the state and appends it to the state file.
+func (o *BatchOrchestrator) save(event *batchEvent) *OpenAIErr {
+	if err := o.apply(event); err != nil {
+		return errCannotSaveState(err)
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errCannotMarshalJSON(err)
+	}
+	if err := appendFile(o.path, append(b, '\n')); err != nil {
+		return errCannotSaveState(err)
+	}
+	return nil
+}
+
+// appendFile appends b to the file at path, creating it when needed.
+func appendFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *batchRequest) succeeded() bool {
+	var result BatchResult[json.RawMessage]
+	return json.Unmarshal(r.Result, &result) == nil && result.Err() == nil
+}
+
+// submit sends the pending requests that are not running in a batch yet,
+// split by endpoint and by the size limits.
+func (o *BatchOrchestrator) submit(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+	builders := map[string]*BatchBuilder{}
+	var endpoints []string
+	flush := func(endpoint string) *OpenAIErr {
+		b := builders[endpoint]
+		delete(builders, endpoint)
+		batch, err := SubmitBatch(api, httpClient, b, o.opts.Metadata)
+		if err != nil {
+			return err
+		}
+		return o.save(&batchEvent{Batch: batch.Id, CustomIds: b.CustomIds()})
+	}
+	for _, id := range o.state.Order {
+		request := o.state.Requests[id]
+		if request.Done || request.Batch != "" {
+			continue
+		}
+		for {
+			b, ok := builders[request.Endpoint]
+			if !ok {
+				b = NewBatchBuilder()
+				b.maxRequests, b.maxSize = o.opts.MaxRequests, o.opts.MaxFileSize
+				builders[request.Endpoint] = b
+				endpoints = append(endpoints, request.Endpoint)
+			}
+			err := b.Add(id, request.Endpoint, request.Body)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ErrBatchFull) {
+				return errInvalidRequest(fmt.Errorf("%s: %w", id, err))
+			}
+			if b.Len() == 0 {
+				// The request alone exceeds the file size limit: it can never be sent.
+				line, err := json.Marshal(BatchResult[json.RawMessage]{CustomId: id, Error: &BatchError{
+					Code:    "request_too_large",
+					Message: fmt.Sprintf("request exceeds the batch file size limit of %d bytes", o.opts.MaxFileSize),
+				}})
+				if err != nil {
+					return errCannotMarshalJSON(err)
+				}
+				if err := o.save(&batchEvent{Results: map[string]*batchOutcome{id: {Result: line, Done: true}}}); err != nil {
+					return err
+				}
+				break
+			}
+			if err := flush(request.Endpoint); err != nil {
+				return err
+			}
+		}
+	}
+	for _, endpoint := range endpoints {
+		if b, ok := builders[endpoint]; ok && b.Len() > 0 {
+			if err := flush(endpoint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collect waits for a batch to finish and records the results of its requests.
+// Requests that failed, or got no result because the batch failed, expired or
+// was cancelled, are resubmitted until they run out of attempts.
+func (o *BatchOrchestrator) collect(ctx context.Context, api OpenAIClient, httpClient HTTPClient, run *batchRun) *OpenAIErr {
+	batch, err := WaitBatch(ctx, api, httpClient, run.Id, o.opts.Poll)
+	if err != nil {
+		return err
+	}
+	results, err := BatchResults[json.RawMessage](api, httpClient, batch)
+	if err != nil {
+		return err
+	}
+	event := &batchEvent{Batch: run.Id, Results: make(map[string]*batchOutcome, len(run.CustomIds))}
+	for _, id := range run.CustomIds {
+		attempts := o.state.Requests[id].Attempts + 1
+		result, ok := results[id]
+		if !ok {
+			result = &BatchResult[json.RawMessage]{CustomId: id, Error: &BatchError{
+				Code:    "batch_" + batch.Status,
+				Message: fmt.Sprintf("no result in %s batch %s", batch.Status, batch.Id),
+			}}
+		}
+		line, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return errCannotMarshalJSON(marshalErr)
+		}
+		event.Results[id] = &batchOutcome{Result: line, Attempts: attempts, Done: result.Err() == nil || attempts >= o.opts.MaxAttempts}
+	}
+	return o.save(event)
+}
+
+// RunBatches submits the pending requests of o, waits for their batches and
+// resubmits the failed requests until every request succeeded or ran out of
+// attempts. It returns the last result of every request keyed by custom id;
+// use BatchResult.Err to find the failed ones. T is the response of the batch
+// endpoint, such as CompletionResponse. Canceling ctx stops waiting, and the
+// batches already submitted are collected by the next run.
+func RunBatches[T any](ctx context.Context, api OpenAIClient, httpClient HTTPClient, o *BatchOrchestrator) (map[string]*BatchResult[T], *OpenAIErr) {
+	for {
+		for _, run := range o.state.Batches {
+			if run.Collected {
+				continue
+			}
+			if err := o.collect(ctx, api, httpClient, run); err != nil {
+				return nil, err
+			}
+		}
+		pending := false
+		for _, request := range o.state.Requests {
+			pending = pending || !request.Done
+		}
+		if !pending {
+			break
+		}
+		if err := o.saveRequests(); err != nil {
+			return nil, err
+		}
+		if err := o.submit(api, httpClient); err != nil {
+			return nil, err
+		}
+	}
+	results := make(map[string]*BatchResult[T], len(o.state.Requests))
+	for id, request := range o.state.Requests {
+		result := new(BatchResult[T])
+		if err := json.Unmarshal(request.Result, result); err != nil {
+			return nil, errCannotDecodeJSON(fmt.Errorf("%s: %w", id, err))
+		}
+		results[id] = result
+	}
+	return results, nil
+}
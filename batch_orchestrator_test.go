@@ -0,0 +1,196 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func addChatRequests(t *testing.T, o *BatchOrchestrator, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		body := &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini", Messages: DefaultMessages{{Role: "user", Content: id}}}
+		if err := AddChatCompletion(o, id, body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestBatchOrchestratorSplit(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(0, answerChat)
+	o, err := NewBatchOrchestrator(filepath.Join(t.TempDir(), "state.json"), &BatchOrchestratorOpts{MaxRequests: 2, Poll: &BatchPollOpts{Interval: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, "q1", "q2", "q3", "q4", "q5")
+	AddEmbedding(o, "e1", &EmbeddingRequest[string]{Model: "text-embedding-3-small", Input: "Hi"})
+	results, openaiErr := RunBatches[CompletionResponse](context.Background(), api, httpClient, o)
+	if openaiErr != nil {
+		t.Fatalf("unexpected error: %v", openaiErr)
+	}
+	if len(httpClient.batches) != 4 {
+		t.Errorf("expected 3 chat batches and 1 embedding batch, got %d", len(httpClient.batches))
+	}
+	for _, batch := range httpClient.batches {
+		if batch.RequestCounts.Total > 2 {
+			t.Errorf("batch %s has %d requests", batch.Id, batch.RequestCounts.Total)
+		}
+	}
+	for _, id := range []string{"q1", "q2", "q3", "q4", "q5"} {
+		if err := results[id].Err(); err != nil || results[id].Response.Body.Choices[0].Message.Content != "answer to "+id {
+			t.Errorf("unexpected result for %s: %+v", id, results[id])
+		}
+	}
+	if succeeded, failed, pending := o.Progress(); succeeded != 6 || failed != 0 || pending != 0 {
+		t.Errorf("unexpected progress: %d succeeded, %d failed, %d pending", succeeded, failed, pending)
+	}
+}
+
+func TestBatchOrchestratorResubmit(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	attempts := map[string]int{}
+	httpClient := NewMockBatchHTTPClient(0, func(request BatchRequest[json.RawMessage]) any {
+		attempts[request.CustomId]++
+		if strings.HasPrefix(request.CustomId, "flaky") && attempts[request.CustomId] == 1 {
+			return nil
+		}
+		return answerChat(request)
+	})
+	httpClient.expire = 1
+	o, err := NewBatchOrchestrator(filepath.Join(t.TempDir(), "state.json"), &BatchOrchestratorOpts{MaxAttempts: 3, Poll: &BatchPollOpts{Interval: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, "q1", "flaky-1", "fail-1")
+	results, openaiErr := RunBatches[CompletionResponse](context.Background(), api, httpClient, o)
+	if openaiErr != nil {
+		t.Fatalf("unexpected error: %v", openaiErr)
+	}
+	// The first batch expires, the second fails flaky-1 and fail-1, the third fail-1 again.
+	if len(httpClient.batches) != 3 {
+		t.Errorf("expected 3 batches, got %d", len(httpClient.batches))
+	}
+	if results["q1"].Err() != nil || results["flaky-1"].Err() != nil {
+		t.Errorf("expected q1 and flaky-1 to succeed, got %v and %v", results["q1"].Err(), results["flaky-1"].Err())
+	}
+	if err := results["fail-1"].Err(); err == nil || err.Error() != "bad request" {
+		t.Errorf("expected fail-1 to fail, got %v", err)
+	}
+	if attempts["fail-1"] != 2 {
+		t.Errorf("expected fail-1 to run twice after the expired batch, ran %d times", attempts["fail-1"])
+	}
+	if succeeded, failed, pending := o.Progress(); succeeded != 2 || failed != 1 || pending != 0 {
+		t.Errorf("unexpected progress: %d succeeded, %d failed, %d pending", succeeded, failed, pending)
+	}
+}
+
+func TestBatchOrchestratorResume(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(3, answerChat)
+	path := filepath.Join(t.TempDir(), "state.json")
+	ids := []string{"q1", "q2", "q3"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &BatchOrchestratorOpts{MaxRequests: 2, Poll: &BatchPollOpts{Interval: time.Millisecond, OnPoll: func(*Batch) { cancel() }}}
+	o, err := NewBatchOrchestrator(path, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, ids...)
+	if _, err := RunBatches[CompletionResponse](ctx, api, httpClient, o); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be interrupted, got %v", err)
+	}
+
+	// A new process loads the state and adds the same requests again.
+	o, err = NewBatchOrchestrator(path, &BatchOrchestratorOpts{MaxRequests: 2, Poll: &BatchPollOpts{Interval: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, ids...)
+	results, openaiErr := RunBatches[CompletionResponse](context.Background(), api, httpClient, o)
+	if openaiErr != nil {
+		t.Fatalf("unexpected error: %v", openaiErr)
+	}
+	if len(httpClient.batches) != 2 {
+		t.Errorf("expected the submitted batches to be resumed, got %d batches", len(httpClient.batches))
+	}
+	for _, id := range ids {
+		if results[id].Err() != nil {
+			t.Errorf("unexpected error for %s: %v", id, results[id].Err())
+		}
+	}
+}
+
+func TestBatchOrchestratorRequestTooLarge(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(0, answerChat)
+	o, err := NewBatchOrchestrator(filepath.Join(t.TempDir(), "state.json"), &BatchOrchestratorOpts{MaxFileSize: 200, Poll: &BatchPollOpts{Interval: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, "q1", fmt.Sprintf("big-%0200d", 0))
+	results, openaiErr := RunBatches[CompletionResponse](context.Background(), api, httpClient, o)
+	if openaiErr != nil {
+		t.Fatalf("unexpected error: %v", openaiErr)
+	}
+	if results["q1"].Err() != nil {
+		t.Errorf("unexpected error for q1: %v", results["q1"].Err())
+	}
+	if err := results[fmt.Sprintf("big-%0200d", 0)].Err(); err == nil || err.Err.Code != "request_too_large" {
+		t.Errorf("expected the big request to be rejected, got %v", err)
+	}
+}
+
+func TestBatchOrchestratorStateFiles(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(0, answerChat)
+	path := filepath.Join(t.TempDir(), "state.json")
+	opts := &BatchOrchestratorOpts{MaxRequests: 2, Poll: &BatchPollOpts{Interval: time.Millisecond}}
+	o, err := NewBatchOrchestrator(path, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, "q1", "q2", "q3")
+	if _, err := RunBatches[CompletionResponse](context.Background(), api, httpClient, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	requests, _ := os.ReadFile(path + ".requests")
+	if lines := strings.Count(string(requests), "\n"); lines != 3 {
+		t.Errorf("expected every request to be saved once, got %d lines", lines)
+	}
+	state, _ := os.ReadFile(path)
+	// Two batches submitted, then collected.
+	if lines := strings.Count(string(state), "\n"); lines != 4 {
+		t.Errorf("expected 4 events, got %d lines", lines)
+	}
+	if strings.Contains(string(state), `"messages"`) {
+		t.Errorf("expected the request bodies not to be saved with the state, got %s", state)
+	}
+
+	// A crash while appending an event leaves a partial line.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	f.WriteString(`{"batch":"batch_`)
+	f.Close()
+	o, err = NewBatchOrchestrator(path, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addChatRequests(t, o, "q1", "q2", "q3", "q4")
+	results, openaiErr := RunBatches[CompletionResponse](context.Background(), api, httpClient, o)
+	if openaiErr != nil {
+		t.Fatalf("unexpected error: %v", openaiErr)
+	}
+	if len(httpClient.batches) != 3 || len(results) != 4 || results["q4"].Err() != nil {
+		t.Errorf("expected only q4 to be sent again, got %d batches and results %+v", len(httpClient.batches), results)
+	}
+	if _, err := NewBatchOrchestrator(path, opts); err != nil {
+		t.Errorf("expected the state to load after the partial line, got %v", err)
+	}
+}
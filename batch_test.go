@@ -0,0 +1,297 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockBatchHTTPClient runs batches against the in-memory files of
+// MockFilesHTTPClient. Every batch is in progress for polls polls, then
+// answers each request with answer, or fails it when answer returns nil.
+// The first expire batches expire instead, without any result.
+type MockBatchHTTPClient struct {
+	*MockFilesHTTPClient
+	polls   int
+	expire  int
+	answer  func(BatchRequest[json.RawMessage]) any
+	batches map[string]*Batch
+}
+
+func NewMockBatchHTTPClient(polls int, answer func(BatchRequest[json.RawMessage]) any) *MockBatchHTTPClient {
+	return &MockBatchHTTPClient{
+		MockFilesHTTPClient: &MockFilesHTTPClient{contents: map[string]string{}},
+		polls:               polls,
+		answer:              answer,
+		batches:             map[string]*Batch{},
+	}
+}
+
+func (c *MockBatchHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	if !strings.Contains(url, "/batches") {
+		return c.MockFilesHTTPClient.Post(url, opts)
+	}
+	path := c.record(http.MethodPost, url, opts)
+	var body CreateBatchRequest
+	if err := json.NewDecoder(opts.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, "/cancel") {
+		batch := c.batches[strings.TrimSuffix(strings.TrimPrefix(path, "/batches/"), "/cancel")]
+		batch.Status = BatchStatusCancelling
+		return c.batchResponse(batch)
+	}
+	if _, ok := c.contents[body.InputFileId]; !ok {
+		return jsonResponse(http.StatusBadRequest, `{"error":{"message":"invalid input file","type":"invalid_request_error"}}`), nil
+	}
+	batch := &Batch{
+		Id:               fmt.Sprintf("batch-%d", len(c.batches)+1),
+		Object:           "batch",
+		Endpoint:         body.Endpoint,
+		InputFileId:      body.InputFileId,
+		CompletionWindow: body.CompletionWindow,
+		Status:           BatchStatusValidating,
+		Metadata:         body.Metadata,
+	}
+	c.batches[batch.Id] = batch
+	return c.batchResponse(batch)
+}
+
+func (c *MockBatchHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	if !strings.Contains(url, "/batches") {
+		return c.MockFilesHTTPClient.Get(url, opts)
+	}
+	path := c.record(http.MethodGet, url, opts)
+	batch := c.batches[strings.TrimPrefix(path, "/batches/")]
+	if batch.Done() {
+		return c.batchResponse(batch)
+	}
+	if batch.Status == BatchStatusCancelling {
+		batch.Status = BatchStatusCancelled
+		return c.batchResponse(batch)
+	}
+	if c.polls > 0 {
+		c.polls--
+		batch.Status = BatchStatusInProgress
+		return c.batchResponse(batch)
+	}
+	if c.expire > 0 {
+		c.expire--
+		batch.Status = BatchStatusExpired
+		return c.batchResponse(batch)
+	}
+	var output, errorsFile bytes.Buffer
+	decoder := json.NewDecoder(strings.NewReader(c.contents[batch.InputFileId]))
+	for {
+		var request BatchRequest[json.RawMessage]
+		if err := decoder.Decode(&request); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		batch.RequestCounts.Total++
+		if answer := c.answer(request); answer != nil {
+			batch.RequestCounts.Completed++
+			line, _ := json.Marshal(map[string]any{"id": "req-" + request.CustomId, "custom_id": request.CustomId, "response": map[string]any{"status_code": 200, "request_id": "r", "body": answer}})
+			output.Write(append(line, '\n'))
+		} else {
+			batch.RequestCounts.Failed++
+			fmt.Fprintf(&errorsFile, `{"id":"req-%s","custom_id":%q,"response":{"status_code":400,"request_id":"r","body":{"error":{"message":"bad request","type":"invalid_request_error"}}}}`+"\n", request.CustomId, request.CustomId)
+		}
+	}
+	batch.Status = BatchStatusCompleted
+	if output.Len() > 0 {
+		batch.OutputFileId = batch.Id + "-output"
+		c.contents[batch.OutputFileId] = output.String()
+	}
+	if errorsFile.Len() > 0 {
+		batch.ErrorFileId = batch.Id + "-errors"
+		c.contents[batch.ErrorFileId] = errorsFile.String()
+	}
+	return c.batchResponse(batch)
+}
+
+func (c *MockBatchHTTPClient) batchResponse(batch *Batch) (*http.Response, error) {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(http.StatusOK, string(b)), nil
+}
+
+// answerChat answers every chat request with its custom id, and fails the ones whose id starts with "fail".
+func answerChat(request BatchRequest[json.RawMessage]) any {
+	if strings.HasPrefix(request.CustomId, "fail") {
+		return nil
+	}
+	return CompletionResponse{ID: request.CustomId, Choices: []Choice{{Message: Message[string]{Role: "assistant", Content: "answer to " + request.CustomId}}}}
+}
+
+func TestBatchBuilder(t *testing.T) {
+	b := NewBatchBuilder()
+	if err := AddChatCompletion(b, "q1", &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini", Messages: DefaultMessages{{Role: "user", Content: "Hi"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddChatCompletion(b, "q1", &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini"}); err == nil {
+		t.Error("expected an error for a duplicate custom_id")
+	}
+	if err := AddEmbedding(b, "e1", &EmbeddingRequest[string]{Model: "text-embedding-3-small", Input: "Hi"}); err == nil {
+		t.Error("expected an error for a different endpoint")
+	}
+	if err := b.Add("", BatchEndpointChatCompletions, struct{}{}); err == nil {
+		t.Error("expected an error for a missing custom_id")
+	}
+	var out strings.Builder
+	if _, err := b.WriteTo(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"custom_id":"q1","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hi"}]}}` + "\n"
+	if out.String() != want {
+		t.Errorf("expected %s, got %s", want, out.String())
+	}
+	if b.Len() != 1 || b.Size() != len(want) || b.Endpoint() != BatchEndpointChatCompletions {
+		t.Errorf("unexpected builder state: %d requests, %d bytes, endpoint %s", b.Len(), b.Size(), b.Endpoint())
+	}
+}
+
+func TestBatchBuilderFull(t *testing.T) {
+	b := NewBatchBuilder()
+	for i := 0; i < MaxBatchRequests; i++ {
+		if err := b.Add(fmt.Sprint(i), BatchEndpointEmbeddings, struct{}{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := b.Add("last", BatchEndpointEmbeddings, struct{}{}); !errors.Is(err, ErrBatchFull) {
+		t.Fatalf("expected ErrBatchFull, got %v", err)
+	}
+	if b.Len() != MaxBatchRequests {
+		t.Errorf("a full batch should be left unchanged, got %d requests", b.Len())
+	}
+	b = NewBatchBuilder()
+	if err := b.Add("big", BatchEndpointEmbeddings, map[string]string{"input": strings.Repeat("a", MaxBatchFileSize)}); !errors.Is(err, ErrBatchFull) {
+		t.Errorf("expected ErrBatchFull, got %v", err)
+	}
+}
+
+func TestBatchRoundTrip(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(2, answerChat)
+	b := NewBatchBuilder()
+	for _, id := range []string{"q1", "q2", "fail-1"} {
+		body := &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini", Messages: DefaultMessages{{Role: "user", Content: id}}}
+		if err := AddChatCompletion(b, id, body); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	batch, err := SubmitBatch(api, httpClient, b, map[string]string{"job": "nightly"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batch.Status != BatchStatusValidating || batch.Endpoint != BatchEndpointChatCompletions || batch.CompletionWindow != DefaultBatchCompletionWindow || batch.Metadata["job"] != "nightly" {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+
+	var statuses []string
+	batch, err = WaitBatch(context.Background(), api, httpClient, batch.Id, &BatchPollOpts{
+		Interval: time.Millisecond,
+		OnPoll:   func(b *Batch) { statuses = append(statuses, b.Status) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(statuses, ",") != "in_progress,in_progress,completed" {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+
+	results, err := BatchResults[CompletionResponse](api, httpClient, batch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, id := range []string{"q1", "q2"} {
+		result := results[id]
+		if result.Err() != nil || result.Response.Body.Choices[0].Message.Content != "answer to "+id {
+			t.Errorf("unexpected result for %s: %+v", id, result)
+		}
+	}
+	if err := results["fail-1"].Err(); err == nil || err.Status() != http.StatusBadRequest || err.Error() != "bad request" {
+		t.Errorf("expected a failed request, got %v", err)
+	}
+}
+
+func TestWaitBatchCancel(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := NewMockBatchHTTPClient(1000, answerChat)
+	b := NewBatchBuilder()
+	AddChatCompletion(b, "q1", &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini"})
+	batch, err := SubmitBatch(api, httpClient, b, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := 0
+	_, err = WaitBatch(ctx, api, httpClient, batch.Id, &BatchPollOpts{
+		Interval: time.Millisecond,
+		OnPoll: func(*Batch) {
+			if polls++; polls == 3 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be canceled, got %v", err)
+	}
+
+	batch, err = CancelBatch(api, httpClient, batch.Id)
+	if err != nil || batch.Status != BatchStatusCancelling {
+		t.Fatalf("unexpected batch %+v, error %v", batch, err)
+	}
+	batch, err = WaitBatch(context.Background(), api, httpClient, batch.Id, &BatchPollOpts{Interval: time.Millisecond})
+	if err != nil || batch.Status != BatchStatusCancelled {
+		t.Errorf("unexpected batch %+v, error %v", batch, err)
+	}
+}
+
+// MockFlakyHTTPClient fails the first failures requests with a server error.
+type MockFlakyHTTPClient struct {
+	HTTPClient
+	failures int
+}
+
+func (c *MockFlakyHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	if c.failures > 0 {
+		c.failures--
+		return jsonResponse(http.StatusServiceUnavailable, ""), nil
+	}
+	return c.HTTPClient.Get(url, opts)
+}
+
+func TestWaitBatchRetries(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	batchClient := NewMockBatchHTTPClient(0, answerChat)
+	b := NewBatchBuilder()
+	AddChatCompletion(b, "q1", &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini"})
+	batch, err := SubmitBatch(api, batchClient, b, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := &BatchPollOpts{Interval: time.Millisecond, MaxRetries: 2}
+	if _, err := WaitBatch(context.Background(), api, &MockFlakyHTTPClient{batchClient, 2}, batch.Id, opts); err != nil {
+		t.Errorf("expected the failed polls to be retried, got %v", err)
+	}
+	if _, err := WaitBatch(context.Background(), api, &MockFlakyHTTPClient{batchClient, 3}, batch.Id, opts); err == nil || err.Status() != http.StatusServiceUnavailable {
+		t.Errorf("expected the retries to be exhausted, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"net/http"
+	"sync"
 
 	"github.com/Simplou/goxios"
 )
@@ -10,6 +11,7 @@ import (
 type Client struct {
 	ctx     context.Context
 	apiKey  string
+	mu      sync.RWMutex
 	headers []goxios.Header
 }
 
@@ -26,6 +28,35 @@ type HTTPClient interface {
 	Get(string, *goxios.RequestOpts) (*http.Response, error)
 }
 
+// HTTPDeleteClient is an HTTPClient that also sends DELETE requests, as goxios
+// clients do. It is only required by the endpoints that delete resources.
+type HTTPDeleteClient interface {
+	HTTPClient
+	Delete(string, *goxios.RequestOpts) (*http.Response, error)
+}
+
+// HTTPClientFactory returns a new HTTPClient. Helpers that send requests from
+// several goroutines use it to give each worker its own client, because clients
+// such as goxios keep per-request state and are not safe for concurrent use.
+type HTTPClientFactory func() HTTPClient
+
+// client returns a new client from f, or fallback when f is nil.
+func (f HTTPClientFactory) client(fallback HTTPClient) HTTPClient {
+	if f == nil {
+		return fallback
+	}
+	return f()
+}
+
+// concurrency returns n when f gives each worker its own client, and 1 otherwise,
+// because the single fallback client is not safe for concurrent use.
+func (f HTTPClientFactory) concurrency(n int) int {
+	if f == nil {
+		return 1
+	}
+	return n
+}
+
 func (c *Client) BaseURL() string {
 	return "https://api.openai.com/v1"
 }
@@ -39,7 +70,7 @@ func (c *Client) ApiKey() string {
 }
 
 func New(ctx context.Context, apiKey string) *Client {
-	openaiClient := &Client{ctx, apiKey, []goxios.Header{}}
+	openaiClient := &Client{ctx: ctx, apiKey: apiKey, headers: []goxios.Header{}}
 	openaiClient.setAuthorizationHeader()
 	return openaiClient
 }
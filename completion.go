@@ -1,7 +1,7 @@
 package openai
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"net/http"
 
 	"github.com/Simplou/goxios"
@@ -32,20 +32,42 @@ func ImageUrl(url string) *imageUrl {
 	return &imageUrl{url}
 }
 
+// ImageDataUrl embeds an encoded image in a data URL, for images that are not
+// publicly reachable. The content type is detected from data.
+func ImageDataUrl(data []byte) *imageUrl {
+	return &imageUrl{"data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)}
+}
+
+// TextContent is a text part of a multimodal input.
+func TextContent(text string) MediaMessage {
+	return MediaMessage{Type: "text", Text: text}
+}
+
+// ImageContent is an image part of a multimodal input.
+func ImageContent(url *imageUrl) MediaMessage {
+	return MediaMessage{Type: "image_url", ImageUrl: url}
+}
+
 // Message represents a message in the conversation.
 type Message[T string | []MediaMessage] struct {
 	Role      string     `json:"role"`
 	Content   T          `json:"content"`
+	Name      string     `json:"name,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallId is the id of the call answered by a tool message.
+	ToolCallId string `json:"tool_call_id,omitempty"`
 }
 
 type ToolCall struct {
-	Id       string `json:"id"`
-	Type     string `json:"type"`
-	Function struct {
-		Name string `json:"name"`
-		Args string `json:"arguments"`
-	} `json:"function"`
+	Id       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall is the function called by a tool call, with its arguments encoded in JSON.
+type FunctionCall struct {
+	Name string `json:"name"`
+	Args string `json:"arguments"`
 }
 
 // Tool represents a tool that can be used during the conversation.
@@ -101,30 +123,21 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// ChatCompletion creates a model response for the conversation. The model
+// defaults to DefaultChatModel, and requests using a feature the model does
+// not support, according to LookupModel, fail without being sent.
 func ChatCompletion[Messages any](api OpenAIClient, httpClient HTTPClient, body *CompletionRequest[Messages]) (*CompletionResponse, *OpenAIErr) {
-	api.AddHeader(contentTypeJSON)
-	b, err := json.Marshal(body)
-	if err != nil {
-		return nil, errCannotMarshalJSON(err)
+	if body.Model == "" {
+		request := *body
+		request.Model = DefaultChatModel
+		body = &request
 	}
-	options := &goxios.RequestOpts{
-		Headers: api.Headers(),
-		Body:    ioReader(b),
+	if err := validateChatModel(body.Model, body.Messages, body.Tools); err != nil {
+		return nil, errInvalidRequest(err)
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/chat/completions", options)
+	res, err := postJSON(api, httpClient, "/chat/completions", body)
 	if err != nil {
-		return nil, errCannotSendRequest(err)
-	}
-	if res.StatusCode >= http.StatusBadRequest {
-		return nil, openaiHttpError(res)
-	}
-	response := new(CompletionResponse)
-	if err := goxios.DecodeJSON(res.Body, response); err != nil {
-		return nil, errCannotDecodeJSON(err)
-	}
-
-	if err := res.Body.Close(); err != nil {
-		return nil, errCloseBody(err)
+		return nil, err
 	}
-	return response, nil
+	return decodeResponse[CompletionResponse](res)
 }
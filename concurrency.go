@@ -0,0 +1,41 @@
+package openai
+
+import "sync"
+
+// parallel calls fn for the indexes 0 to n-1 from at most concurrency goroutines,
+// each with its own client from newClient, or with fallback when newClient is nil.
+// Once a call fails no new indexes are started. The error of each index is returned.
+func parallel(n, concurrency int, newClient HTTPClientFactory, fallback HTTPClient, fn func(httpClient HTTPClient, i int) error) []error {
+	if concurrency <= 0 || concurrency > n {
+		concurrency = n
+	}
+	jobs := make(chan int)
+	errs := make([]error, n)
+	failed := make(chan struct{})
+	var once sync.Once
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			httpClient := newClient.client(fallback)
+			for i := range jobs {
+				if err := fn(httpClient, i); err != nil {
+					errs[i] = err
+					once.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-failed:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
@@ -1,17 +1,13 @@
 package openai
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/rand"
-	"net/http"
 	"sort"
 	"strings"
 	"time"
-
-	"github.com/Simplou/goxios"
 )
 
 type EmbeddingRequest[Input string | []string] struct {
@@ -45,31 +41,21 @@ type (
 )
 
 // CreateEmbedding sends a request to create embeddings for the given input.
+// The model defaults to DefaultEmbeddingModel.
 func CreateEmbedding[Input string | []string, Encoding []float64 | Base64](api OpenAIClient, httpClient HTTPClient, body *EmbeddingRequest[Input]) (*EmbeddingResponse[Encoding], *OpenAIErr) {
-	api.AddHeader(contentTypeJSON)
-	b, err := json.Marshal(body)
-	if err != nil {
-		return nil, errCannotMarshalJSON(err)
+	if body.Model == "" {
+		request := *body
+		request.Model = DefaultEmbeddingModel
+		body = &request
 	}
-	options := goxios.RequestOpts{
-		Headers: api.Headers(),
-		Body:    ioReader(b),
+	if err := validateEmbeddingModel(body.Model, body.Dimensions); err != nil {
+		return nil, errInvalidRequest(err)
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/embeddings", &options)
+	res, err := postJSON(api, httpClient, "/embeddings", body)
 	if err != nil {
-		return nil, errCannotSendRequest(err)
-	}
-	if res.StatusCode >= http.StatusBadRequest {
-		return nil, openaiHttpError(res)
-	}
-	response := new(EmbeddingResponse[Encoding])
-	if err := goxios.DecodeJSON(res.Body, response); err != nil {
-		return nil, errCannotDecodeJSON(err)
-	}
-	if err := res.Body.Close(); err != nil {
-		return nil, errCloseBody(err)
+		return nil, err
 	}
-	return response, nil
+	return decodeResponse[EmbeddingResponse[Encoding]](res)
 }
 
 type ChunkTextOpts struct {
@@ -120,7 +106,7 @@ func ChunksSummary(client OpenAIClient, httpClient HTTPClient, relevantChunks []
 // chunkSummary generates a summary for the given chunk and query.
 func chunkSummary(client OpenAIClient, httpClient HTTPClient, chunk, query string) (string, error) {
 	response, err := ChatCompletion(client, httpClient, &CompletionRequest[DefaultMessages]{
-		Model: "gpt-3.5-turbo",
+		Model: DefaultChatModel,
 		Messages: DefaultMessages{
 			{Role: "system", Content: "Você deve resumir a resposta para a pergunta do usuário usando o conteúdo de forma clara e concisa."},
 			{Role: "user", Content: fmt.Sprintf("Desenvolva uma resposta curta e clara para a pergunta %s baseada no seguinte conteúdo: %s", query, chunk)},
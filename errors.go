@@ -1,15 +1,21 @@
 package openai
 
 import (
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/Simplou/goxios"
 )
 
 var (
 	internalError = func(err error, t string) *OpenAIErr {
-		return NewOpenAIErr(err, 500, t)
+		openaiErr := NewOpenAIErr(err, 500, t)
+		if openaiErr != nil {
+			openaiErr.internal = true
+		}
+		return openaiErr
 	}
 	errCannotOpenFile = func(err error) *OpenAIErr {
 		return internalError(err, "cannot_open_file")
@@ -29,14 +35,45 @@ var (
 	errCannotMarshalJSON = func(err error) *OpenAIErr {
 		return internalError(err, "cannot_marshal_json")
 	}
+	errCannotReadBody = func(err error) *OpenAIErr {
+		return internalError(err, "cannot_read_body")
+	}
+	errEmptyBody = func(err error) *OpenAIErr {
+		return internalError(err, "empty_response_body")
+	}
 	errCloseBody = func(err error) *OpenAIErr {
 		return internalError(err, "close_body_error")
 	}
+	errCannotSaveState = func(err error) *OpenAIErr {
+		return internalError(err, "cannot_save_state")
+	}
+	errCanceled = func(err error) *OpenAIErr {
+		return internalError(err, "canceled")
+	}
+	invalidRequestError = func(err error, t string) *OpenAIErr {
+		return NewOpenAIErr(err, 400, t)
+	}
+	errInvalidRequest = func(err error) *OpenAIErr {
+		return invalidRequestError(err, "invalid_request_error")
+	}
+	errInvalidImage = func(err error) *OpenAIErr {
+		return invalidRequestError(err, "invalid_image")
+	}
+	errInvalidAudio = func(err error) *OpenAIErr {
+		return invalidRequestError(err, "invalid_audio")
+	}
+	errContentFlagged = func(err error) *OpenAIErr {
+		return invalidRequestError(err, "content_flagged")
+	}
 )
 
 type OpenAIErr struct {
 	Err    JSONErr `json:"error"`
 	status int
+	cause  error
+	// internal marks the errors of the client itself, which did not come from
+	// an API response.
+	internal bool
 }
 
 func (o *OpenAIErr) Error() string {
@@ -47,6 +84,15 @@ func (o *OpenAIErr) Status() int {
 	return o.status
 }
 
+// Unwrap returns the error the OpenAIErr was built from, if any, so errors.Is
+// and errors.As can match it.
+func (o *OpenAIErr) Unwrap() error {
+	if o == nil {
+		return nil
+	}
+	return o.cause
+}
+
 func NewOpenAIErr(err error, statusCode int, t string) *OpenAIErr {
 	if err != nil {
 		return &OpenAIErr{
@@ -55,6 +101,7 @@ func NewOpenAIErr(err error, statusCode int, t string) *OpenAIErr {
 				Type:    t,
 			},
 			status: statusCode,
+			cause:  err,
 		}
 	}
 	return nil
@@ -73,13 +120,41 @@ func closeBody(body io.ReadCloser, err error) *OpenAIErr {
 	return nil
 }
 
+// asOpenAIErr returns err itself when it is an *OpenAIErr, and wrap(err) otherwise.
+func asOpenAIErr(err error, wrap func(error) *OpenAIErr) *OpenAIErr {
+	if openaiErr := new(OpenAIErr); errors.As(err, &openaiErr) {
+		return openaiErr
+	}
+	return wrap(err)
+}
+
+// maxErrorBodySize bounds how much of an error response is read.
+const maxErrorBodySize = 64 << 10
+
+// openaiHttpError builds the error of a failed response and closes its body. Bodies
+// that are not OpenAI JSON errors, such as proxy error pages or empty bodies, keep
+// the response status and use the body text, or the status text, as message.
 func openaiHttpError(res *http.Response) *OpenAIErr {
+	b, readErr := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if closeErr := closeBody(res.Body, nil); closeErr != nil && readErr == nil {
+		readErr = closeErr
+	}
 	err := new(OpenAIErr)
-	if err := goxios.DecodeJSON(res.Body, err); err != nil {
-		return closeBody(res.Body, err)
+	if jsonErr := goxios.UnmarshalJSON(b, err); jsonErr != nil || err.Err.Message == "" {
+		message := ""
+		if jsonErr != nil {
+			message = strings.TrimSpace(string(b))
+		}
+		if message == "" {
+			message = http.StatusText(res.StatusCode)
+		}
+		if readErr != nil {
+			message += ": " + readErr.Error()
+		}
+		err.Err = JSONErr{Message: message, Type: "http_error"}
 	}
 	err.status = res.StatusCode
-	return closeBody(res.Body, err)
+	return err
 }
 
 type JSONErr struct {
@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"os"
 
@@ -35,12 +34,12 @@ func tts() {
 			}
 			log.Fatal(string(b))
 		}
-		defer audio.Close()
-		b, err := io.ReadAll(audio)
+		file, err := os.Create(audioFilePath)
 		if err != nil {
 			panic(err)
 		}
-		if err := os.WriteFile(audioFilePath, b, os.ModePerm); err != nil {
+		defer file.Close()
+		if _, err := audio.Stream(ctx, file); err != nil {
 			panic(err)
 		}
 	}
@@ -57,3 +56,14 @@ func whisper() {
 	}
 	println(transcription.Text)
 }
+
+func translation(voicemailPath string) {
+	translation, err := openai.Translation(client, httpClient, &openai.TranslationsRequestBody{
+		Model:         openai.DefaultTranscriptionModel,
+		AudioFilePath: voicemailPath,
+	})
+	if err != nil {
+		panic(err)
+	}
+	println(translation.Text)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -114,41 +115,27 @@ func chatByEmbedding(largeText string, query string) {
 }
 
 func chatModerator(customerMessage string) {
-	moderation, err := openai.Moderator(client, httpClient, &openai.ModerationRequest[string]{
-		Input: customerMessage,
-	})
-	if err != nil {
-		log.Println(err)
-	}
-	categories := make([]string, 0)
-	for _, v := range moderation.Results {
-		if v.Flagged {
-			for category, value := range v.Categories {
-				if value {
-					categories = append(categories, category)
-				}
-			}
-		}
-	}
-	if len(categories) == 0 {
-		res, err := openai.ChatCompletion[openai.DefaultMessages](
-			client,
-			httpClient,
-			&openai.CompletionRequest[openai.DefaultMessages]{
-				Model: "gpt-3.5-turbo",
-				Messages: openai.DefaultMessages{
-					{Role: "user", Content: customerMessage},
-				},
+	res, err := openai.ModeratedChatCompletion(
+		client,
+		httpClient,
+		&openai.ModerationGuard{ModerateInput: true, ModerateOutput: true},
+		&openai.CompletionRequest[openai.DefaultMessages]{
+			Model: "gpt-3.5-turbo",
+			Messages: openai.DefaultMessages{
+				{Role: "user", Content: customerMessage},
 			},
-		)
-		if err != nil {
-			log.Println(err)
+		},
+	)
+	if err != nil {
+		var flagged *openai.ModerationError
+		if errors.As(err, &flagged) {
+			s := strings.Join(flagged.Categories, ", ")
+			moderatorMessage := fmt.Sprintf("Your statement contains several disrespectful things: (%s)", s)
+			log.Println(moderatorMessage)
+			return
 		}
-		log.Println(res.Choices[0].Message.Content)
-	} else {
-		s := strings.Join(categories, ", ")
-		moderatorMessage := fmt.Sprintf("Your statement contains several disrespectful things: (%s)", s)
-		log.Println(moderatorMessage)
+		log.Println(err)
+		return
 	}
-
+	log.Println(res.Choices[0].Message.Content)
 }
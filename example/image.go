@@ -37,10 +37,10 @@ func image() {
 		prompt := fmt.Sprintf("generate %s mascot %s from tool %s creating a robot", animal, mascot.Name, mascot.Tool)
 		if !ImageGenerated(mascot.imageFilePath) {
 			body := &openai.ImagesGenerationsRequestBody{
-				Model:  "dall-e-3",
+				Model:  openai.DallE3ImageModel,
 				Prompt: prompt,
 				N:      1,
-				Size:   "1024x1024",
+				Size:   openai.DallE3Size1024x1024,
 				Style:  openai.VividImageStyle().String(),
 			}
 			res, err := openai.ImagesGenerations(client, httpClient, body)
@@ -76,10 +76,10 @@ func vision() {
 	filePath := imagePath + "gopher.png"
 	if !ImageGenerated(filePath) {
 		body := &openai.ImagesGenerationsRequestBody{
-			Model:  "dall-e-3",
+			Model:  openai.DallE3ImageModel,
 			Prompt: prompt,
 			N:      1,
-			Size:   "1024x1024",
+			Size:   openai.DallE3Size1024x1024,
 			Style:  openai.VividImageStyle().String(),
 		}
 		res, err := openai.ImagesGenerations(client, httpClient, body)
@@ -31,6 +31,7 @@ func main() {
 	//functionCall()
 	//tts()
 	//whisper()
+	//translation(audioFilePath)
 	//image()
 	//vision()
 }
@@ -0,0 +1,168 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/Simplou/goxios"
+)
+
+// Purposes of uploaded files.
+const (
+	FilePurposeAssistants = "assistants"
+	FilePurposeBatch      = "batch"
+	FilePurposeFineTune   = "fine-tune"
+	FilePurposeVision     = "vision"
+	FilePurposeUserData   = "user_data"
+	FilePurposeEvals      = "evals"
+)
+
+// MaxFileSize is the largest file accepted by the files endpoint.
+const MaxFileSize = 512 << 20
+
+type (
+	UploadFileRequest struct {
+		// Purpose is one of the FilePurpose constants.
+		Purpose            string
+		Filename, FilePath string
+		// File is read instead of FilePath when set. Filename is required.
+		File io.Reader
+		// ContentType of File. Guessed from the Filename extension when empty.
+		ContentType string
+	}
+
+	// File is the metadata of an uploaded file. Times are Unix timestamps in seconds.
+	File struct {
+		Id            string `json:"id"`
+		Object        string `json:"object"`
+		Bytes         int64  `json:"bytes"`
+		CreatedAt     int64  `json:"created_at"`
+		ExpiresAt     int64  `json:"expires_at,omitempty"`
+		Filename      string `json:"filename"`
+		Purpose       string `json:"purpose"`
+		Status        string `json:"status,omitempty"`
+		StatusDetails string `json:"status_details,omitempty"`
+	}
+
+	// ListFilesParams filters and paginates ListFiles.
+	ListFilesParams struct {
+		ListParams
+		Purpose string
+	}
+)
+
+func (body *UploadFileRequest) formFile() (formFile, io.Closer, *OpenAIErr) {
+	if body.Purpose == "" {
+		return formFile{}, nil, errInvalidRequest(errors.New("purpose is required"))
+	}
+	filename := body.Filename
+	if filename == "" && body.FilePath != "" {
+		filename = filepath.Base(body.FilePath)
+	}
+	if filename == "" {
+		return formFile{}, nil, errInvalidRequest(errors.New("filename is required"))
+	}
+	contentType := body.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	content, closer := body.File, io.Closer(nil)
+	if content == nil {
+		if body.FilePath == "" {
+			return formFile{}, nil, errInvalidRequest(errors.New("either File or FilePath is required"))
+		}
+		file, err := os.Open(body.FilePath)
+		if err != nil {
+			return formFile{}, nil, errCannotOpenFile(err)
+		}
+		content, closer = file, file
+	}
+	if size, ok := readerSize(content); ok && size > MaxFileSize {
+		if closer != nil {
+			closer.Close()
+		}
+		return formFile{}, nil, errInvalidRequest(fmt.Errorf("%s: file must be at most 512MB, got %d bytes", filename, size))
+	}
+	return formFile{Field: "file", Filename: filename, ContentType: contentType, Content: content}, closer, nil
+}
+
+// UploadFile uploads a file for use with other endpoints. The file is streamed to
+// the API, so memory use does not grow with its size.
+func UploadFile(api OpenAIClient, httpClient HTTPClient, body *UploadFileRequest) (*File, *OpenAIErr) {
+	file, closer, openaiErr := body.formFile()
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	form := multipartForm([]formField{{"purpose", body.Purpose}}, []formFile{file})
+	res, openaiErr := postMultipart(api, httpClient, "/files", form)
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	return decodeResponse[File](res)
+}
+
+func (p *ListFilesParams) queryParams() []goxios.QueryParam {
+	if p == nil {
+		return nil
+	}
+	params := p.ListParams.queryParams()
+	if p.Purpose != "" {
+		params = append(params, goxios.QueryParam{Key: "purpose", Value: p.Purpose})
+	}
+	return params
+}
+
+// ListFiles returns a page of the uploaded files.
+func ListFiles(api OpenAIClient, httpClient HTTPClient, params *ListFilesParams) (*List[File], *OpenAIErr) {
+	return getJSON[List[File]](api, httpClient, "/files", params.queryParams()...)
+}
+
+// ListAllFiles returns the uploaded files of every page, starting after params.After.
+func ListAllFiles(api OpenAIClient, httpClient HTTPClient, params *ListFilesParams) ([]File, *OpenAIErr) {
+	page := ListFilesParams{}
+	if params != nil {
+		page = *params
+	}
+	return listAll(page.After, func(f File) string { return f.Id }, func(after string) (*List[File], *OpenAIErr) {
+		page.After = after
+		return ListFiles(api, httpClient, &page)
+	})
+}
+
+// RetrieveFile returns the metadata of a file.
+func RetrieveFile(api OpenAIClient, httpClient HTTPClient, fileId string) (*File, *OpenAIErr) {
+	if fileId == "" {
+		return nil, errInvalidRequest(errors.New("file id is required"))
+	}
+	return getJSON[File](api, httpClient, "/files"+pathID(fileId))
+}
+
+// FileContent streams the content of a file. The caller must close it.
+func FileContent(api OpenAIClient, httpClient HTTPClient, fileId string) (io.ReadCloser, *OpenAIErr) {
+	if fileId == "" {
+		return nil, errInvalidRequest(errors.New("file id is required"))
+	}
+	res, err := get(api, httpClient, "/files"+pathID(fileId)+"/content")
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// DeleteFile deletes a file.
+func DeleteFile(api OpenAIClient, httpClient HTTPDeleteClient, fileId string) (*Deletion, *OpenAIErr) {
+	if fileId == "" {
+		return nil, errInvalidRequest(errors.New("file id is required"))
+	}
+	return deleteJSON[Deletion](api, httpClient, "/files"+pathID(fileId))
+}
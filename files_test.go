@@ -0,0 +1,181 @@
+package openai
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockFilesHTTPClient serves the files endpoints from memory.
+type MockFilesHTTPClient struct {
+	files    []File
+	contents map[string]string
+	requests []string
+}
+
+func (c *MockFilesHTTPClient) record(method, url string, opts *goxios.RequestOpts) string {
+	path := strings.TrimPrefix(url, "https://fake.api.openai.com/v1")
+	for i, param := range opts.QueryParams {
+		sep := "&"
+		if i == 0 {
+			sep = "?"
+		}
+		path += fmt.Sprintf("%s%s=%v", sep, param.Key, param.Value)
+	}
+	c.requests = append(c.requests, method+" "+path)
+	return path
+}
+
+func jsonResponse(statusCode int, body string) *http.Response {
+	return &http.Response{StatusCode: statusCode, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func (c *MockFilesHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	c.record(http.MethodPost, url, opts)
+	var contentType string
+	for _, header := range opts.Headers {
+		if header.Key == "Content-Type" {
+			contentType = fmt.Sprint(header.Value)
+		}
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	form, err := multipart.NewReader(opts.Body, params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		return nil, err
+	}
+	header := form.File["file"][0]
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	content, _ := io.ReadAll(file)
+	id := fmt.Sprintf("file-%d", len(c.files)+1)
+	c.files = append(c.files, File{Id: id, Object: "file", Bytes: int64(len(content)), Filename: header.Filename, Purpose: form.Value["purpose"][0]})
+	c.contents[id] = string(content)
+	return jsonResponse(http.StatusOK, fmt.Sprintf(`{"id":%q,"object":"file","bytes":%d,"filename":%q,"purpose":%q}`, id, len(content), header.Filename, form.Value["purpose"][0])), nil
+}
+
+func (c *MockFilesHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	path := c.record(http.MethodGet, url, opts)
+	switch {
+	case strings.HasPrefix(path, "/files?"):
+		after := ""
+		for _, param := range opts.QueryParams {
+			if param.Key == "after" {
+				after = fmt.Sprint(param.Value)
+			}
+		}
+		start := 0
+		for i, file := range c.files {
+			if file.Id == after {
+				start = i + 1
+			}
+		}
+		end := min(start+1, len(c.files))
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{"object":"list","data":[{"id":%q}],"has_more":%t}`, c.files[start].Id, end < len(c.files))), nil
+	case strings.HasSuffix(path, "/content"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/files/"), "/content")
+		content, ok := c.contents[id]
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{"error":{"message":"No such File object","type":"invalid_request_error"}}`), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(content))}, nil
+	default:
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{"id":%q,"object":"file"}`, strings.TrimPrefix(path, "/files/"))), nil
+	}
+}
+
+func (c *MockFilesHTTPClient) Delete(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	path := c.record(http.MethodDelete, url, opts)
+	return jsonResponse(http.StatusOK, fmt.Sprintf(`{"id":%q,"object":"file","deleted":true}`, strings.TrimPrefix(path, "/files/"))), nil
+}
+
+func TestFiles(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	httpClient := &MockFilesHTTPClient{contents: map[string]string{}}
+
+	for _, content := range []string{`{"custom_id":"1"}`, `{"custom_id":"2"}`} {
+		file, err := UploadFile(api, httpClient, &UploadFileRequest{Purpose: FilePurposeBatch, Filename: "requests.jsonl", File: strings.NewReader(content)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if file.Purpose != FilePurposeBatch || file.Filename != "requests.jsonl" || file.Bytes != int64(len(content)) {
+			t.Errorf("unexpected file: %+v", file)
+		}
+	}
+
+	files, err := ListAllFiles(api, httpClient, &ListFilesParams{ListParams: ListParams{Limit: 1}, Purpose: FilePurposeBatch})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 || files[0].Id != "file-1" || files[1].Id != "file-2" {
+		t.Errorf("expected both pages, got %+v", files)
+	}
+
+	file, err := RetrieveFile(api, httpClient, "file-2")
+	if err != nil || file.Id != "file-2" {
+		t.Errorf("unexpected file %+v, error %v", file, err)
+	}
+
+	content, err := FileContent(api, httpClient, "file-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := io.ReadAll(content)
+	content.Close()
+	if string(b) != `{"custom_id":"1"}` {
+		t.Errorf("unexpected content %q", b)
+	}
+	if _, err := FileContent(api, httpClient, "file-9"); err == nil || err.Status() != http.StatusNotFound {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	deletion, err := DeleteFile(api, httpClient, "file-1")
+	if err != nil || !deletion.Deleted || deletion.Id != "file-1" {
+		t.Errorf("unexpected deletion %+v, error %v", deletion, err)
+	}
+
+	want := []string{
+		"POST /files",
+		"POST /files",
+		"GET /files?limit=1&purpose=batch",
+		"GET /files?limit=1&after=file-1&purpose=batch",
+		"GET /files/file-2",
+		"GET /files/file-1/content",
+		"GET /files/file-9/content",
+		"DELETE /files/file-1",
+	}
+	if strings.Join(httpClient.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected requests:\n%s", strings.Join(httpClient.requests, "\n"))
+	}
+}
+
+func TestUploadFileValidation(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	tests := map[string]*UploadFileRequest{
+		"MissingPurpose":  {Filename: "a.jsonl", File: strings.NewReader("{}")},
+		"MissingFilename": {Purpose: FilePurposeBatch, File: strings.NewReader("{}")},
+		"MissingFile":     {Purpose: FilePurposeBatch, Filename: "a.jsonl"},
+		"MissingPath":     {Purpose: FilePurposeBatch, FilePath: "testdata/missing.jsonl"},
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			httpClient := &MockFilesHTTPClient{contents: map[string]string{}}
+			if _, err := UploadFile(api, httpClient, body); err == nil {
+				t.Error("expected an error")
+			}
+			if len(httpClient.requests) != 0 {
+				t.Error("invalid upload should not be sent")
+			}
+		})
+	}
+}
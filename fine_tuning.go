@@ -0,0 +1,285 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Fine-tuning job statuses.
+const (
+	FineTuningStatusValidatingFiles = "validating_files"
+	FineTuningStatusQueued          = "queued"
+	FineTuningStatusRunning         = "running"
+	FineTuningStatusSucceeded       = "succeeded"
+	FineTuningStatusFailed          = "failed"
+	FineTuningStatusCancelled       = "cancelled"
+)
+
+// Fine-tuning methods.
+const (
+	FineTuningMethodSupervised = "supervised"
+	FineTuningMethodDPO        = "dpo"
+)
+
+// HyperparameterValue is a hyperparameter set to a number, or chosen by the
+// API when Auto is set.
+type HyperparameterValue struct {
+	Auto  bool
+	Value float64
+}
+
+// HyperparameterAuto lets the API choose the value of a hyperparameter.
+var HyperparameterAuto = &HyperparameterValue{Auto: true}
+
+// Hyperparameter sets a hyperparameter to v.
+func Hyperparameter(v float64) *HyperparameterValue {
+	return &HyperparameterValue{Value: v}
+}
+
+func (h HyperparameterValue) MarshalJSON() ([]byte, error) {
+	if h.Auto {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(h.Value)
+}
+
+func (h *HyperparameterValue) UnmarshalJSON(b []byte) error {
+	var auto string
+	if json.Unmarshal(b, &auto) == nil {
+		if auto != "auto" {
+			return fmt.Errorf("invalid hyperparameter %q", auto)
+		}
+		*h = HyperparameterValue{Auto: true}
+		return nil
+	}
+	*h = HyperparameterValue{}
+	return json.Unmarshal(b, &h.Value)
+}
+
+func (h *HyperparameterValue) String() string {
+	if h.Auto {
+		return "auto"
+	}
+	return strconv.FormatFloat(h.Value, 'g', -1, 64)
+}
+
+type (
+	FineTuningHyperparameters struct {
+		BatchSize              *HyperparameterValue `json:"batch_size,omitempty"`
+		LearningRateMultiplier *HyperparameterValue `json:"learning_rate_multiplier,omitempty"`
+		NEpochs                *HyperparameterValue `json:"n_epochs,omitempty"`
+		// Beta is only used by the dpo method.
+		Beta *HyperparameterValue `json:"beta,omitempty"`
+	}
+
+	// FineTuningMethod selects how the model is trained, supervised by default.
+	FineTuningMethod struct {
+		Type       string                  `json:"type"`
+		Supervised *FineTuningMethodParams `json:"supervised,omitempty"`
+		DPO        *FineTuningMethodParams `json:"dpo,omitempty"`
+	}
+
+	FineTuningMethodParams struct {
+		Hyperparameters *FineTuningHyperparameters `json:"hyperparameters,omitempty"`
+	}
+
+	CreateFineTuningJobRequest struct {
+		Model string `json:"model"`
+		// TrainingFile and ValidationFile are ids of files uploaded with the
+		// fine-tune purpose.
+		TrainingFile   string `json:"training_file"`
+		ValidationFile string `json:"validation_file,omitempty"`
+		// Hyperparameters of the supervised method. Use Method for the others.
+		Hyperparameters *FineTuningHyperparameters `json:"hyperparameters,omitempty"`
+		Method          *FineTuningMethod          `json:"method,omitempty"`
+		// Suffix is added to the name of the fine-tuned model, up to 64 characters.
+		Suffix   string            `json:"suffix,omitempty"`
+		Seed     *int              `json:"seed,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+
+	// FineTuningJob is a fine-tuning job. Times are Unix timestamps in seconds.
+	FineTuningJob struct {
+		Id              string                    `json:"id"`
+		Object          string                    `json:"object"`
+		Model           string                    `json:"model"`
+		CreatedAt       int64                     `json:"created_at"`
+		FinishedAt      int64                     `json:"finished_at,omitempty"`
+		EstimatedFinish int64                     `json:"estimated_finish,omitempty"`
+		FineTunedModel  string                    `json:"fine_tuned_model,omitempty"`
+		OrganizationId  string                    `json:"organization_id"`
+		ResultFiles     []string                  `json:"result_files"`
+		Status          string                    `json:"status"`
+		TrainingFile    string                    `json:"training_file"`
+		ValidationFile  string                    `json:"validation_file,omitempty"`
+		Hyperparameters FineTuningHyperparameters `json:"hyperparameters"`
+		Method          *FineTuningMethod         `json:"method,omitempty"`
+		TrainedTokens   int                       `json:"trained_tokens,omitempty"`
+		Seed            int                       `json:"seed"`
+		Error           *JSONErr                  `json:"error,omitempty"`
+		Metadata        map[string]string         `json:"metadata,omitempty"`
+	}
+
+	// FineTuningEvent is a status update or training metric of a job.
+	FineTuningEvent struct {
+		Id        string          `json:"id"`
+		Object    string          `json:"object"`
+		CreatedAt int64           `json:"created_at"`
+		Level     string          `json:"level"`
+		Message   string          `json:"message"`
+		Type      string          `json:"type,omitempty"`
+		Data      json.RawMessage `json:"data,omitempty"`
+	}
+
+	// FineTuningCheckpoint is a model saved at the end of a training epoch.
+	FineTuningCheckpoint struct {
+		Id                       string             `json:"id"`
+		Object                   string             `json:"object"`
+		CreatedAt                int64              `json:"created_at"`
+		FineTunedModelCheckpoint string             `json:"fine_tuned_model_checkpoint"`
+		FineTuningJobId          string             `json:"fine_tuning_job_id"`
+		StepNumber               int                `json:"step_number"`
+		Metrics                  map[string]float64 `json:"metrics"`
+	}
+)
+
+// Done reports whether the job reached a final status.
+func (j *FineTuningJob) Done() bool {
+	switch j.Status {
+	case FineTuningStatusSucceeded, FineTuningStatusFailed, FineTuningStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func CreateFineTuningJob(api OpenAIClient, httpClient HTTPClient, body *CreateFineTuningJobRequest) (*FineTuningJob, *OpenAIErr) {
+	if body.Model == "" || body.TrainingFile == "" {
+		return nil, errInvalidRequest(errors.New("model and training_file are required"))
+	}
+	if len(body.Suffix) > 64 {
+		return nil, errInvalidRequest(fmt.Errorf("suffix must be at most 64 characters, got %d", len(body.Suffix)))
+	}
+	res, err := postJSON(api, httpClient, "/fine_tuning/jobs", body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[FineTuningJob](res)
+}
+
+func ListFineTuningJobs(api OpenAIClient, httpClient HTTPClient, params *ListParams) (*List[FineTuningJob], *OpenAIErr) {
+	return getJSON[List[FineTuningJob]](api, httpClient, "/fine_tuning/jobs", params.queryParams()...)
+}
+
+func RetrieveFineTuningJob(api OpenAIClient, httpClient HTTPClient, jobId string) (*FineTuningJob, *OpenAIErr) {
+	if jobId == "" {
+		return nil, errInvalidRequest(errors.New("job id is required"))
+	}
+	return getJSON[FineTuningJob](api, httpClient, "/fine_tuning/jobs"+pathID(jobId))
+}
+
+func CancelFineTuningJob(api OpenAIClient, httpClient HTTPClient, jobId string) (*FineTuningJob, *OpenAIErr) {
+	if jobId == "" {
+		return nil, errInvalidRequest(errors.New("job id is required"))
+	}
+	res, err := postJSON(api, httpClient, "/fine_tuning/jobs"+pathID(jobId)+"/cancel", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[FineTuningJob](res)
+}
+
+// ListFineTuningEvents returns a page of the events of a job, newest first.
+func ListFineTuningEvents(api OpenAIClient, httpClient HTTPClient, jobId string, params *ListParams) (*List[FineTuningEvent], *OpenAIErr) {
+	if jobId == "" {
+		return nil, errInvalidRequest(errors.New("job id is required"))
+	}
+	return getJSON[List[FineTuningEvent]](api, httpClient, "/fine_tuning/jobs"+pathID(jobId)+"/events", params.queryParams()...)
+}
+
+func ListFineTuningCheckpoints(api OpenAIClient, httpClient HTTPClient, jobId string, params *ListParams) (*List[FineTuningCheckpoint], *OpenAIErr) {
+	if jobId == "" {
+		return nil, errInvalidRequest(errors.New("job id is required"))
+	}
+	return getJSON[List[FineTuningCheckpoint]](api, httpClient, "/fine_tuning/jobs"+pathID(jobId)+"/checkpoints", params.queryParams()...)
+}
+
+// FineTuningWaitOpts configures WaitFineTuningJob. Zero values use the defaults.
+type FineTuningWaitOpts struct {
+	// Interval is the first delay between two polls, 5s by default. It doubles
+	// after every poll up to MaxInterval, 1min by default.
+	Interval, MaxInterval time.Duration
+	// MaxRetries bounds the consecutive failed polls, 5 by default.
+	MaxRetries int
+	// OnPoll, when set, is called with every job status received.
+	OnPoll func(*FineTuningJob)
+	// OnEvent, when set, is called with the new events of the job, oldest first.
+	OnEvent func(*FineTuningEvent)
+}
+
+// WaitFineTuningJob polls a job until it reaches a final status and returns the
+// name of the fine-tuned model. Jobs that failed or were cancelled return an
+// error of type "fine_tuning_failed". Canceling ctx stops waiting, not the job.
+func WaitFineTuningJob(ctx context.Context, api OpenAIClient, httpClient HTTPClient, jobId string, opts *FineTuningWaitOpts) (string, *OpenAIErr) {
+	if opts == nil {
+		opts = &FineTuningWaitOpts{}
+	}
+	var lastEvent string
+	fetch := func() (*FineTuningJob, *OpenAIErr) {
+		job, err := RetrieveFineTuningJob(api, httpClient, jobId)
+		if err != nil || opts.OnEvent == nil {
+			return job, err
+		}
+		events, err := newFineTuningEvents(api, httpClient, jobId, lastEvent)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			opts.OnEvent(&events[i])
+			lastEvent = events[i].Id
+		}
+		return job, nil
+	}
+	job, err := poll(ctx, opts.Interval, opts.MaxInterval, opts.MaxRetries, fetch, func(job *FineTuningJob) bool {
+		if opts.OnPoll != nil {
+			opts.OnPoll(job)
+		}
+		return job.Done()
+	})
+	if err != nil {
+		return "", err
+	}
+	if job.Status != FineTuningStatusSucceeded {
+		message := fmt.Sprintf("fine-tuning job %s %s", job.Id, job.Status)
+		if job.Error != nil && job.Error.Message != "" {
+			message += ": " + job.Error.Message
+		}
+		return "", invalidRequestError(errors.New(message), "fine_tuning_failed")
+	}
+	return job.FineTunedModel, nil
+}
+
+// newFineTuningEvents returns the events more recent than lastEvent, newest first.
+func newFineTuningEvents(api OpenAIClient, httpClient HTTPClient, jobId, lastEvent string) ([]FineTuningEvent, *OpenAIErr) {
+	var events []FineTuningEvent
+	params := &ListParams{Limit: 100}
+	for {
+		page, err := ListFineTuningEvents(api, httpClient, jobId, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range page.Data {
+			if event.Id == lastEvent {
+				return events, nil
+			}
+			events = append(events, event)
+		}
+		if !page.HasMore || len(page.Data) == 0 {
+			return events, nil
+		}
+		params.After = page.Data[len(page.Data)-1].Id
+	}
+}
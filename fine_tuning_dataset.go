@@ -0,0 +1,342 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MinFineTuningExamples is the smallest dataset accepted by the fine-tuning API.
+	MinFineTuningExamples = 10
+	// DefaultFineTuningExampleTokens is the longest example the fine-tuning API
+	// trains on. Longer examples are truncated.
+	DefaultFineTuningExampleTokens = 65536
+	// maxDatasetLine bounds the size of a line read by ValidateDataset.
+	maxDatasetLine = 64 << 20
+)
+
+// FineTuningExample is a line of a chat fine-tuning dataset. The assistant
+// messages are what the model learns to answer, tool calls included.
+type FineTuningExample struct {
+	Messages          DefaultMessages `json:"messages"`
+	Tools             []Tool          `json:"tools,omitempty"`
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+}
+
+// DatasetWriter writes fine-tuning examples as JSONL, ready to be uploaded with
+// the fine-tune purpose.
+type DatasetWriter struct {
+	w io.Writer
+	n int
+}
+
+func NewDatasetWriter(w io.Writer) *DatasetWriter {
+	return &DatasetWriter{w: w}
+}
+
+// Write checks example and appends it to the dataset. Invalid examples are not
+// written.
+func (d *DatasetWriter) Write(example *FineTuningExample) error {
+	messages := make([]datasetMessage, len(example.Messages))
+	for i, message := range example.Messages {
+		messages[i] = datasetMessage{
+			Role:       message.Role,
+			Content:    &message.Content,
+			Name:       message.Name,
+			ToolCalls:  message.ToolCalls,
+			ToolCallId: message.ToolCallId,
+		}
+	}
+	if problems := checkExample(messages); len(problems) > 0 {
+		return fmt.Errorf("example %d: %s", d.n+1, problems[0])
+	}
+	b, err := json.Marshal(example)
+	if err != nil {
+		return err
+	}
+	if _, err := d.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	d.n++
+	return nil
+}
+
+// Count returns the number of examples written.
+func (d *DatasetWriter) Count() int {
+	return d.n
+}
+
+type (
+	// datasetLine is a line of a dataset as read by ValidateDataset.
+	datasetLine struct {
+		Messages          []datasetMessage `json:"messages"`
+		Tools             []Tool           `json:"tools,omitempty"`
+		ParallelToolCalls *bool            `json:"parallel_tool_calls,omitempty"`
+	}
+
+	datasetMessage struct {
+		Role       string     `json:"role"`
+		Content    *string    `json:"content"`
+		Name       string     `json:"name,omitempty"`
+		ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+		ToolCallId string     `json:"tool_call_id,omitempty"`
+		// Weight set to 0 excludes an assistant message from training.
+		Weight *int `json:"weight,omitempty"`
+	}
+)
+
+// errMissingAssistant tells the examples without an assistant message apart
+// from the other problems.
+const errMissingAssistant = "no assistant message"
+
+// checkExample returns the problems of an example, in message order.
+func checkExample(messages []datasetMessage) []string {
+	if len(messages) == 0 {
+		return []string{"no messages"}
+	}
+	var problems []string
+	calls := map[string]bool{}
+	assistant := false
+	for i, message := range messages {
+		problem := func(format string, args ...any) {
+			problems = append(problems, fmt.Sprintf("message %d: ", i+1)+fmt.Sprintf(format, args...))
+		}
+		switch message.Role {
+		case "system", "user":
+		case "assistant":
+			assistant = true
+		case "tool":
+			if !calls[message.ToolCallId] {
+				problem("tool_call_id %q does not answer a previous tool call", message.ToolCallId)
+			}
+		default:
+			problem("invalid role %q", message.Role)
+		}
+		if (message.Content == nil || *message.Content == "") && len(message.ToolCalls) == 0 {
+			problem("missing content")
+		}
+		if len(message.ToolCalls) > 0 && message.Role != "assistant" {
+			problem("only assistant messages can have tool calls")
+		}
+		if message.Weight != nil && (message.Role != "assistant" || *message.Weight < 0 || *message.Weight > 1) {
+			problem("weight must be 0 or 1 and only set on assistant messages")
+		}
+		for _, call := range message.ToolCalls {
+			switch {
+			case call.Id == "":
+				problem("tool call without id")
+			case call.Function.Name == "":
+				problem("tool call %s without function name", call.Id)
+			case !json.Valid([]byte(call.Function.Args)):
+				problem("tool call %s arguments are not valid JSON", call.Id)
+			}
+			calls[call.Id] = true
+		}
+	}
+	if !assistant {
+		problems = append(problems, errMissingAssistant)
+	}
+	return problems
+}
+
+// DatasetValidationOpts configures ValidateDataset. Zero values use the defaults.
+type DatasetValidationOpts struct {
+	// Model is the model to fine-tune, DefaultChatModel by default. Its
+	// fine-tuning price gives the estimated cost.
+	Model string
+	// MaxTokens is the longest example in tokens. It defaults to
+	// DefaultFineTuningExampleTokens, or the context window of Model when smaller.
+	MaxTokens int
+	// Epochs is the number of epochs trained. By default it is chosen from the
+	// size of the dataset, as the API does when n_epochs is auto.
+	Epochs int
+	// CountTokens counts the tokens of a text, EstimateTokens by default. Set it
+	// to a real tokenizer for exact counts.
+	CountTokens func(text string) int
+}
+
+// DatasetError is a problem found on a line of a dataset.
+type DatasetError struct {
+	// Line is the line number, starting at 1.
+	Line    int
+	Message string
+}
+
+func (e *DatasetError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// DatasetReport is the result of ValidateDataset.
+type DatasetReport struct {
+	// Examples is the number of lines read.
+	Examples int
+	// Errors lists the lines that are not valid chat examples.
+	Errors []*DatasetError
+	// MissingAssistant lists the lines without an assistant message.
+	MissingAssistant []int
+	// Tokens is the token count of every line, zero for the invalid ones.
+	Tokens []int
+	// TooLong lists the lines over MaxTokens, which the API truncates.
+	TooLong   []int
+	MaxTokens int
+	// TotalTokens is the token count of the dataset.
+	TotalTokens int
+	Epochs      int
+	// TrainedTokens is the number of tokens billed: every example, truncated
+	// to MaxTokens, once per epoch.
+	TrainedTokens int
+	// EstimatedCost is the estimated price of the job in USD, zero when the
+	// fine-tuning price of the model is unknown.
+	EstimatedCost float64
+}
+
+// Valid reports whether the dataset can be used as is: it is large enough,
+// every line is a chat example with an assistant message, and none is truncated.
+func (r *DatasetReport) Valid() bool {
+	return r.Examples >= MinFineTuningExamples && len(r.Errors) == 0 && len(r.MissingAssistant) == 0 && len(r.TooLong) == 0
+}
+
+// ValidateDataset reads a chat fine-tuning dataset in JSONL and reports its
+// problems, its token counts and the estimated cost of training on it. The
+// returned error is only set when r cannot be read.
+func ValidateDataset(r io.Reader, opts *DatasetValidationOpts) (*DatasetReport, error) {
+	if opts == nil {
+		opts = &DatasetValidationOpts{}
+	}
+	model := opts.Model
+	if model == "" {
+		model = DefaultChatModel
+	}
+	capabilities, _ := LookupModel(model)
+	countTokens := opts.CountTokens
+	if countTokens == nil {
+		countTokens = EstimateTokens
+	}
+	report := &DatasetReport{MaxTokens: opts.MaxTokens}
+	if report.MaxTokens <= 0 {
+		report.MaxTokens = DefaultFineTuningExampleTokens
+		if capabilities.ContextWindow > 0 {
+			report.MaxTokens = min(report.MaxTokens, capabilities.ContextWindow)
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxDatasetLine)
+	trained := 0
+	for scanner.Scan() {
+		report.Examples++
+		lineNumber := report.Examples
+		report.Tokens = append(report.Tokens, 0)
+		invalid := func(message string) {
+			report.Errors = append(report.Errors, &DatasetError{Line: lineNumber, Message: message})
+		}
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			invalid("empty line")
+			continue
+		}
+		var line datasetLine
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&line); err != nil {
+			invalid("invalid JSON: " + err.Error())
+			continue
+		}
+		problems := checkExample(line.Messages)
+		for _, problem := range problems {
+			if problem == errMissingAssistant {
+				report.MissingAssistant = append(report.MissingAssistant, lineNumber)
+			} else {
+				invalid(problem)
+			}
+		}
+		if len(problems) > 0 {
+			continue
+		}
+		tokens := exampleTokens(&line, countTokens)
+		report.Tokens[lineNumber-1] = tokens
+		report.TotalTokens += tokens
+		if tokens > report.MaxTokens {
+			report.TooLong = append(report.TooLong, lineNumber)
+		}
+		trained += min(tokens, report.MaxTokens)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	report.Epochs = opts.Epochs
+	if report.Epochs <= 0 {
+		report.Epochs = fineTuningEpochs(report.Examples)
+	}
+	report.TrainedTokens = trained * report.Epochs
+	report.EstimatedCost = float64(report.TrainedTokens) * capabilities.FineTuningPrice / 1e6
+	return report, nil
+}
+
+// exampleTokens counts the tokens of an example the way chat models see it:
+// a few tokens frame every message and prime the reply.
+func exampleTokens(line *datasetLine, countTokens func(string) int) int {
+	tokens := 3
+	for _, message := range line.Messages {
+		tokens += 3 + countTokens(message.Role)
+		if message.Content != nil {
+			tokens += countTokens(*message.Content)
+		}
+		if message.Name != "" {
+			tokens += 1 + countTokens(message.Name)
+		}
+		for _, call := range message.ToolCalls {
+			tokens += 3 + countTokens(call.Function.Name) + countTokens(call.Function.Args)
+		}
+	}
+	if len(line.Tools) > 0 {
+		if b, err := json.Marshal(line.Tools); err == nil {
+			tokens += countTokens(string(b))
+		}
+	}
+	return tokens
+}
+
+// fineTuningEpochs returns the number of epochs the API picks for a dataset of
+// n examples: 3, raised or lowered to train on 100 to 25000 examples in total.
+func fineTuningEpochs(n int) int {
+	const epochs, minExamples, maxExamples, maxEpochs = 3, 100, 25000, 25
+	switch {
+	case n == 0:
+		return epochs
+	case n*epochs < minExamples:
+		return min(maxEpochs, int(math.Ceil(float64(minExamples)/float64(n))))
+	case n*epochs > maxExamples:
+		return max(1, maxExamples/n)
+	}
+	return epochs
+}
+
+// EstimateTokens estimates the number of tokens of text without a tokenizer:
+// about four characters per token for English words, and one token per
+// punctuation mark or non-ASCII character, which overestimates most other
+// languages.
+func EstimateTokens(text string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,136 @@
+package openai
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func weatherExample(city string) *FineTuningExample {
+	return &FineTuningExample{
+		Messages: DefaultMessages{
+			{Role: "system", Content: "You are a weather assistant."},
+			{Role: "user", Content: "What's the weather in " + city + "?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{Id: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather", Args: `{"city":"` + city + `"}`}}}},
+			{Role: "tool", ToolCallId: "call_1", Content: `{"celsius":21}`},
+			{Role: "assistant", Content: "It is 21°C in " + city + "."},
+		},
+		Tools: []Tool{{Type: "function", Function: Function{Name: "get_weather", Description: "Current weather of a city"}}},
+	}
+}
+
+func TestDatasetWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewDatasetWriter(&buf)
+	for _, city := range []string{"Lisbon", "Paris", "Tokyo", "Lima", "Oslo", "Cairo", "Quito", "Seoul", "Rome", "Bern"} {
+		if err := w.Write(weatherExample(city)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invalid := []*FineTuningExample{
+		{},
+		{Messages: DefaultMessages{{Role: "user", Content: "Hi"}}},
+		{Messages: DefaultMessages{{Role: "user", Content: "Hi"}, {Role: "tool", ToolCallId: "call_9", Content: "{}"}, {Role: "assistant", Content: "Hello"}}},
+		{Messages: DefaultMessages{{Role: "bot", Content: "Hi"}, {Role: "assistant", Content: "Hello"}}},
+	}
+	for _, example := range invalid {
+		if err := w.Write(example); err == nil {
+			t.Errorf("expected an error for %+v", example.Messages)
+		}
+	}
+	if w.Count() != 10 {
+		t.Errorf("expected 10 examples, got %d", w.Count())
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 10 {
+		t.Errorf("expected 10 lines, got %d", lines)
+	}
+	if !strings.Contains(buf.String(), `"tool_call_id":"call_1"`) || !strings.Contains(buf.String(), `"arguments":"{\"city\":\"Lisbon\"}"`) {
+		t.Errorf("tool calls are not serialized: %s", buf.String())
+	}
+
+	report, err := ValidateDataset(&buf, &DatasetValidationOpts{Model: "gpt-4o-mini-2024-07-18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid() {
+		t.Fatalf("expected a valid dataset, got %+v", report)
+	}
+	if report.Examples != 10 || len(report.Tokens) != 10 || report.Tokens[0] == 0 {
+		t.Errorf("unexpected token counts %v", report.Tokens)
+	}
+	if report.Epochs != 10 {
+		t.Errorf("expected 10 epochs for 10 examples, got %d", report.Epochs)
+	}
+	if report.TrainedTokens != report.TotalTokens*10 {
+		t.Errorf("expected %d trained tokens, got %d", report.TotalTokens*10, report.TrainedTokens)
+	}
+	if cost := float64(report.TrainedTokens) * 3 / 1e6; math.Abs(report.EstimatedCost-cost) > 1e-9 {
+		t.Errorf("expected a cost of %f, got %f", cost, report.EstimatedCost)
+	}
+}
+
+func TestValidateDataset(t *testing.T) {
+	dataset := strings.Join([]string{
+		`{"messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello"}]}`,
+		`{"messages":[{"role":"user","content":"Hi"}]}`,
+		`not json`,
+		``,
+		`{"messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello","extra":1}]}`,
+		`{"messages":[{"role":"user","content":["Hi"]},{"role":"assistant","content":"Hello"}]}`,
+		`{"messages":[{"role":"user","content":"Hi"},{"role":"assistant","tool_calls":[{"id":"c","type":"function","function":{"name":"f","arguments":"{"}}]}]}`,
+		`{"messages":[{"role":"user","content":"` + strings.Repeat("word ", 50) + `"},{"role":"assistant","content":"Hello","weight":0}]}`,
+	}, "\n")
+	report, err := ValidateDataset(strings.NewReader(dataset), &DatasetValidationOpts{Model: "unknown-model", MaxTokens: 40, Epochs: 2, CountTokens: func(text string) int { return len(strings.Fields(text)) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid() {
+		t.Fatal("expected an invalid dataset")
+	}
+	if report.Examples != 8 {
+		t.Errorf("expected 8 lines, got %d", report.Examples)
+	}
+	var lines []int
+	for _, err := range report.Errors {
+		lines = append(lines, err.Line)
+	}
+	if want := []int{3, 4, 5, 6, 7}; !slices.Equal(lines, want) {
+		t.Errorf("expected errors on lines %v, got %v", want, report.Errors)
+	}
+	if !slices.Equal(report.MissingAssistant, []int{2}) {
+		t.Errorf("expected line 2 without assistant, got %v", report.MissingAssistant)
+	}
+	// 3 reply tokens, then 3 per message plus its role and content.
+	if report.Tokens[0] != 3+(3+1+1)+(3+1+1) {
+		t.Errorf("unexpected token count %d", report.Tokens[0])
+	}
+	if report.Tokens[7] != 3+(3+1+50)+(3+1+1) || !slices.Equal(report.TooLong, []int{8}) {
+		t.Errorf("expected line 8 over the limit, got %d tokens and %v", report.Tokens[7], report.TooLong)
+	}
+	if report.TrainedTokens != (report.Tokens[0]+40)*2 {
+		t.Errorf("expected truncated examples to be billed up to the limit, got %d", report.TrainedTokens)
+	}
+	if report.EstimatedCost != 0 {
+		t.Errorf("expected no cost for an unknown model, got %f", report.EstimatedCost)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := map[string]int{
+		"":                     0,
+		"Hello world":          4,
+		"Hello, world!":        6,
+		"internationalization": 5,
+		"日本":                   2,
+	}
+	for text, want := range tests {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+	if fineTuningEpochs(10) != 10 || fineTuningEpochs(100) != 3 || fineTuningEpochs(20000) != 1 {
+		t.Error("unexpected default epochs")
+	}
+}
@@ -0,0 +1,168 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockFineTuningHTTPClient runs one job through the given statuses, one per
+// poll, adding an event for each of them.
+type MockFineTuningHTTPClient struct {
+	statuses []string
+	job      *FineTuningJob
+	events   []FineTuningEvent
+	created  []byte
+}
+
+func (c *MockFineTuningHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(url, "/cancel") {
+		c.statuses = []string{FineTuningStatusCancelled}
+		c.job.Status = FineTuningStatusRunning
+	} else {
+		c.created = b
+		var body CreateFineTuningJobRequest
+		if err := json.Unmarshal(b, &body); err != nil {
+			return nil, err
+		}
+		c.job = &FineTuningJob{Id: "ftjob-1", Object: "fine_tuning.job", Model: body.Model, TrainingFile: body.TrainingFile, Status: FineTuningStatusValidatingFiles}
+	}
+	return c.response(c.job)
+}
+
+func (c *MockFineTuningHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	switch {
+	case strings.HasSuffix(url, "/events"):
+		page := List[FineTuningEvent]{Object: "list"}
+		start := 0
+		for _, param := range opts.QueryParams {
+			if param.Key == "after" {
+				for i, event := range c.events {
+					if event.Id == param.Value {
+						start = i + 1
+					}
+				}
+			}
+		}
+		// Two events per page, newest first.
+		end := min(start+2, len(c.events))
+		page.Data, page.HasMore = c.events[start:end], end < len(c.events)
+		return c.response(page)
+	case strings.HasSuffix(url, "/checkpoints"):
+		return c.response(List[FineTuningCheckpoint]{Object: "list", Data: []FineTuningCheckpoint{{Id: "ftckpt-1", StepNumber: 10, Metrics: map[string]float64{"train_loss": 0.5}}}})
+	}
+	if len(c.statuses) > 0 {
+		c.job.Status, c.statuses = c.statuses[0], c.statuses[1:]
+		event := FineTuningEvent{Id: fmt.Sprintf("ftevent-%d", len(c.events)+1), Level: "info", Message: "status " + c.job.Status}
+		c.events = append([]FineTuningEvent{event}, c.events...)
+		switch c.job.Status {
+		case FineTuningStatusSucceeded:
+			c.job.FineTunedModel = "ft:" + c.job.Model + ":org::1"
+		case FineTuningStatusFailed:
+			c.job.Error = &JSONErr{Message: "invalid training file", Code: "invalid_training_file"}
+		}
+	}
+	return c.response(c.job)
+}
+
+func (c *MockFineTuningHTTPClient) response(body any) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(http.StatusOK, string(b)), nil
+}
+
+func TestHyperparameterValue(t *testing.T) {
+	body := FineTuningHyperparameters{NEpochs: Hyperparameter(3), BatchSize: HyperparameterAuto, LearningRateMultiplier: Hyperparameter(0.1)}
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"batch_size":"auto","learning_rate_multiplier":0.1,"n_epochs":3}`; string(b) != want {
+		t.Errorf("expected %s, got %s", want, b)
+	}
+	var decoded FineTuningHyperparameters
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.BatchSize.Auto || decoded.NEpochs.Value != 3 || decoded.LearningRateMultiplier.String() != "0.1" {
+		t.Errorf("unexpected hyperparameters: %+v", decoded)
+	}
+	if err := json.Unmarshal([]byte(`{"n_epochs":"many"}`), &decoded); err == nil {
+		t.Error("expected an error for an invalid hyperparameter")
+	}
+}
+
+func TestWaitFineTuningJob(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockFineTuningHTTPClient{statuses: []string{FineTuningStatusQueued, FineTuningStatusRunning, FineTuningStatusRunning, FineTuningStatusSucceeded}}
+	job, err := CreateFineTuningJob(api, httpClient, &CreateFineTuningJobRequest{
+		Model:           "gpt-4o-mini-2024-07-18",
+		TrainingFile:    "file-train",
+		ValidationFile:  "file-valid",
+		Hyperparameters: &FineTuningHyperparameters{NEpochs: Hyperparameter(2)},
+		Suffix:          "classifier",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"model":"gpt-4o-mini-2024-07-18","training_file":"file-train","validation_file":"file-valid","hyperparameters":{"n_epochs":2},"suffix":"classifier"}`; string(httpClient.created) != want {
+		t.Errorf("expected %s, got %s", want, httpClient.created)
+	}
+
+	var messages []string
+	model, err := WaitFineTuningJob(context.Background(), api, httpClient, job.Id, &FineTuningWaitOpts{
+		Interval: time.Millisecond,
+		OnEvent:  func(event *FineTuningEvent) { messages = append(messages, event.Message) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if model != "ft:gpt-4o-mini-2024-07-18:org::1" {
+		t.Errorf("unexpected model %q", model)
+	}
+	if want := "status queued,status running,status running,status succeeded"; strings.Join(messages, ",") != want {
+		t.Errorf("expected events %s, got %s", want, strings.Join(messages, ","))
+	}
+
+	checkpoints, err := ListFineTuningCheckpoints(api, httpClient, job.Id, nil)
+	if err != nil || len(checkpoints.Data) != 1 || checkpoints.Data[0].Metrics["train_loss"] != 0.5 {
+		t.Errorf("unexpected checkpoints %+v, error %v", checkpoints, err)
+	}
+}
+
+func TestWaitFineTuningJobFailure(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	opts := &FineTuningWaitOpts{Interval: time.Millisecond}
+
+	httpClient := &MockFineTuningHTTPClient{statuses: []string{FineTuningStatusFailed}}
+	job, _ := CreateFineTuningJob(api, httpClient, &CreateFineTuningJobRequest{Model: "gpt-4o-mini", TrainingFile: "file-train"})
+	if _, err := WaitFineTuningJob(context.Background(), api, httpClient, job.Id, opts); err == nil || err.Err.Type != "fine_tuning_failed" || !strings.Contains(err.Error(), "invalid training file") {
+		t.Errorf("expected the job to fail, got %v", err)
+	}
+
+	httpClient = &MockFineTuningHTTPClient{statuses: []string{FineTuningStatusRunning, FineTuningStatusRunning, FineTuningStatusRunning}}
+	job, _ = CreateFineTuningJob(api, httpClient, &CreateFineTuningJobRequest{Model: "gpt-4o-mini", TrainingFile: "file-train"})
+	if _, err := CancelFineTuningJob(api, httpClient, job.Id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := WaitFineTuningJob(context.Background(), api, httpClient, job.Id, opts); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("expected the job to be cancelled, got %v", err)
+	}
+
+	if _, err := CreateFineTuningJob(api, httpClient, &CreateFineTuningJobRequest{Model: "gpt-4o-mini"}); err == nil {
+		t.Error("expected an error without a training file")
+	}
+}
@@ -1,6 +1,8 @@
 package openai
 
 import (
+	"strings"
+
 	"github.com/Simplou/goxios"
 )
 
@@ -12,10 +14,29 @@ func (c *Client) setAuthorizationHeader() {
 	c.headers = append(c.headers, goxios.Header{Key: "Authorization", Value: "Bearer " + c.apiKey})
 }
 
+// AddHeader sets h on every following request, replacing a header with the same key.
 func (c *Client) AddHeader(h goxios.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, header := range c.headers {
+		if strings.EqualFold(header.Key, h.Key) {
+			c.headers[i] = h
+			return
+		}
+	}
 	c.headers = append(c.headers, h)
 }
 
 func (c *Client) Headers() []goxios.Header {
-	return c.headers
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]goxios.Header{}, c.headers...)
+}
+
+// requestHeaders returns the client headers followed by extra without adding extra
+// to the client, so concurrent requests with different content types, such as
+// multipart boundaries, don't overwrite each other.
+func requestHeaders(api OpenAIClient, extra ...goxios.Header) []goxios.Header {
+	headers := append([]goxios.Header{}, api.Headers()...)
+	return append(headers, extra...)
 }
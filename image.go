@@ -1,15 +1,26 @@
 package openai
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	"os"
-
-	"github.com/Simplou/goxios"
+	"path/filepath"
+	"strconv"
 )
 
+// MaxImageFileSize is the largest mask, and the largest image accepted by the
+// dall-e-2 edits and variations.
+const MaxImageFileSize = 4 << 20
+
+// MaxGPTImageFileSize is the largest image accepted by the gpt-image-1 edits.
+const MaxGPTImageFileSize = 25 << 20
+
 type imageStyle string
 
 func (is imageStyle) String() string {
@@ -26,67 +37,412 @@ func VividImageStyle() imageStyle {
 	return imageStyle("vivid")
 }
 
+const (
+	DallE2ImageModel   = "dall-e-2"
+	DallE3ImageModel   = "dall-e-3"
+	GPTImage1Model     = "gpt-image-1"
+	defaultImageModel  = DallE2ImageModel
+	ImageResponseURL   = "url"      // Return images as URLs, valid for 60 minutes.
+	ImageResponseB64   = "b64_json" // Return images as base64-encoded JSON.
+	defaultImageNumber = 1
+)
+
+type ImageSize string
+
+func (is ImageSize) String() string {
+	return string(is)
+}
+
+// Sizes supported by each image model.
+const (
+	DallE2Size256x256     ImageSize = "256x256"
+	DallE2Size512x512     ImageSize = "512x512"
+	DallE2Size1024x1024   ImageSize = "1024x1024"
+	DallE3Size1024x1024   ImageSize = "1024x1024"
+	DallE3Size1792x1024   ImageSize = "1792x1024"
+	DallE3Size1024x1792   ImageSize = "1024x1792"
+	GPTImageSize1024x1024 ImageSize = "1024x1024"
+	GPTImageSize1536x1024 ImageSize = "1536x1024"
+	GPTImageSize1024x1536 ImageSize = "1024x1536"
+	GPTImageSizeAuto      ImageSize = "auto"
+)
+
+// imageModelSpec describes the parameters accepted by an image model.
+type imageModelSpec struct {
+	sizes          []ImageSize
+	maxN           int
+	qualities      []string
+	style          bool
+	responseFormat bool
+	gptImage       bool       // Supports background, output_format, output_compression and moderation.
+	input          imageInput // The images accepted by the edits and variations endpoints.
+}
+
+// imageInput describes the image files accepted by a model.
+type imageInput struct {
+	maxFileSize  int
+	contentTypes []string
+	square       bool
+	transparency bool // Edits without a mask need transparent areas in the image.
+}
+
+var (
+	// unknownModelImageInput accepts the images of any known model.
+	unknownModelImageInput = imageInput{maxFileSize: MaxGPTImageFileSize, contentTypes: []string{"image/png", "image/jpeg", "image/webp"}}
+	maskImageInput         = imageInput{maxFileSize: MaxImageFileSize, contentTypes: []string{"image/png"}}
+)
+
+var imageModelSpecs = map[string]imageModelSpec{
+	DallE2ImageModel: {
+		sizes:          []ImageSize{DallE2Size256x256, DallE2Size512x512, DallE2Size1024x1024},
+		maxN:           10,
+		qualities:      []string{"standard"},
+		responseFormat: true,
+		input:          imageInput{maxFileSize: MaxImageFileSize, contentTypes: []string{"image/png"}, square: true, transparency: true},
+	},
+	DallE3ImageModel: {
+		sizes:          []ImageSize{DallE3Size1024x1024, DallE3Size1792x1024, DallE3Size1024x1792},
+		maxN:           1,
+		qualities:      []string{"standard", "hd"},
+		style:          true,
+		responseFormat: true,
+	},
+	GPTImage1Model: {
+		sizes:     []ImageSize{GPTImageSize1024x1024, GPTImageSize1536x1024, GPTImageSize1024x1536, GPTImageSizeAuto},
+		maxN:      10,
+		qualities: []string{"low", "medium", "high", "auto"},
+		gptImage:  true,
+		input:     imageInput{maxFileSize: MaxGPTImageFileSize, contentTypes: []string{"image/png", "image/jpeg", "image/webp"}},
+	},
+}
+
+// The edits and variations endpoints accept fewer models than generations.
+var (
+	imageEditsModelSpecs = map[string]imageModelSpec{
+		DallE2ImageModel: imageModelSpecs[DallE2ImageModel],
+		GPTImage1Model:   imageModelSpecs[GPTImage1Model],
+	}
+	imageVariationsModelSpecs = map[string]imageModelSpec{
+		DallE2ImageModel: imageModelSpecs[DallE2ImageModel],
+	}
+)
+
 type (
 	ImagesGenerationsRequestBody struct {
-		Model  string `json:"model"`
-		Prompt string `json:"prompt"`
-		N      int    `json:"n"`
-		Size   string `json:"size"`
-		Style  string `json:"style,omitempty"` //This param is only supported for dall-e-3.
+		Model   string    `json:"model"`
+		Prompt  string    `json:"prompt"`
+		N       int       `json:"n,omitempty"`
+		Size    ImageSize `json:"size,omitempty"`
+		Style   string    `json:"style,omitempty"`   //This param is only supported for dall-e-3.
+		Quality string    `json:"quality,omitempty"` // standard or hd for dall-e-3; low, medium, high or auto for gpt-image-1.
+		// The format in which the generated images are returned, ImageResponseURL or ImageResponseB64.
+		// This param is not supported for gpt-image-1, which always returns base64-encoded images.
+		ResponseFormat string `json:"response_format,omitempty"`
+		// A unique identifier representing your end-user, which can help OpenAI to monitor and detect abuse.
+		User string `json:"user,omitempty"`
+		// The params below are only supported for gpt-image-1.
+		Background        string `json:"background,omitempty"`         // transparent, opaque or auto.
+		OutputFormat      string `json:"output_format,omitempty"`      // png, jpeg or webp.
+		OutputCompression *int   `json:"output_compression,omitempty"` // 0-100, for jpeg and webp only.
+		Moderation        string `json:"moderation,omitempty"`         // low or auto.
+	}
+
+	// ImageData is a single generated image.
+	ImageData struct {
+		Url           string `json:"url,omitempty"`
+		B64JSON       string `json:"b64_json,omitempty"`
+		RevisedPrompt string `json:"revised_prompt,omitempty"` // The prompt used by dall-e-3 after rewriting.
 	}
 
 	ImagesGenerationsResponse struct {
-		Created int64 `json:"created"`
-		Data    []struct {
-			Url string `json:"url"`
-		} `json:"data"`
+		Created int64       `json:"created"`
+		Data    []ImageData `json:"data"`
 	}
 )
 
-func (igr *ImagesGenerationsResponse) Download(httpClient HTTPClient, filePaths []string) error {
-	if len(filePaths) != len(igr.Data) {
-		return errors.New("number of file paths does not match number of images")
-	}
-	for i, image := range igr.Data {
-		res, err := httpClient.Get(image.Url, &goxios.RequestOpts{})
-		if err != nil {
-			return err
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
 		}
-		defer res.Body.Close()
+	}
+	return false
+}
 
-		file, err := os.Create(filePaths[i])
-		if err != nil {
-			return err
+// validateImageParams checks the params shared by the image endpoints against the
+// spec of the model in specs, the model specs of endpoint. Known image models missing
+// from specs are not supported by the endpoint. Unknown models are not validated so
+// new models can be used before they are added here.
+func validateImageParams(specs map[string]imageModelSpec, endpoint, model string, n int, size ImageSize, responseFormat string) (imageModelSpec, bool, error) {
+	if model == "" {
+		model = defaultImageModel
+	}
+	spec, ok := specs[model]
+	if !ok {
+		if _, known := imageModelSpecs[model]; known {
+			return spec, true, fmt.Errorf("%s does not support image %s", model, endpoint)
 		}
-		defer file.Close()
-
-		_, err = io.Copy(file, res.Body)
-		if err != nil {
-			return err
+		return spec, false, nil
+	}
+	if n < 0 || n > spec.maxN {
+		return spec, true, fmt.Errorf("%s supports n between 1 and %d, got %d", model, spec.maxN, n)
+	}
+	if size != "" && !contains(spec.sizes, size) {
+		return spec, true, fmt.Errorf("%s does not support size %s, expected one of %v", model, size, spec.sizes)
+	}
+	if responseFormat != "" {
+		if !spec.responseFormat {
+			return spec, true, fmt.Errorf("%s does not support response_format", model)
+		}
+		if responseFormat != ImageResponseURL && responseFormat != ImageResponseB64 {
+			return spec, true, fmt.Errorf("invalid response_format %s", responseFormat)
 		}
 	}
+	return spec, true, nil
+}
+
+func (body *ImagesGenerationsRequestBody) validate() error {
+	if body.Prompt == "" {
+		return errors.New("prompt is required")
+	}
+	spec, ok, err := validateImageParams(imageModelSpecs, "generations", body.Model, body.N, body.Size, body.ResponseFormat)
+	if err != nil || !ok {
+		return err
+	}
+	model := body.Model
+	if model == "" {
+		model = defaultImageModel
+	}
+	if body.Style != "" && !spec.style {
+		return fmt.Errorf("%s does not support style", model)
+	}
+	if body.Quality != "" && !contains(spec.qualities, body.Quality) {
+		return fmt.Errorf("%s does not support quality %s, expected one of %v", model, body.Quality, spec.qualities)
+	}
+	if !spec.gptImage && (body.Background != "" || body.OutputFormat != "" || body.OutputCompression != nil || body.Moderation != "") {
+		return fmt.Errorf("%s does not support background, output_format, output_compression or moderation", model)
+	}
+	if c := body.OutputCompression; c != nil && (*c < 0 || *c > 100) {
+		return fmt.Errorf("output_compression must be between 0 and 100, got %d", *c)
+	}
 	return nil
 }
 
 func ImagesGenerations(api OpenAIClient, httpClient HTTPClient, body *ImagesGenerationsRequestBody) (*ImagesGenerationsResponse, *OpenAIErr) {
-	api.AddHeader(contentTypeJSON)
-	b, err := json.Marshal(body)
+	if err := body.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	res, err := postJSON(api, httpClient, "/images/generations", body)
 	if err != nil {
-		return nil, errCannotMarshalJSON(err)
+		return nil, err
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/images/generations", &goxios.RequestOpts{
-		Body:    ioReader(b),
-		Headers: api.Headers(),
-	})
+	return decodeResponse[ImagesGenerationsResponse](res)
+}
+
+// ImageFile is an image uploaded to the edits and variations endpoints: a PNG
+// file for dall-e-2, or a PNG, JPEG or WebP file for gpt-image-1. Use
+// ImageFromPath, ImageFromReader or ImageFromImage to build one.
+type ImageFile struct {
+	Filename string
+	data     []byte
+	err      error
+}
+
+// ImageFromPath reads the image stored at path.
+func ImageFromPath(path string) *ImageFile {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, errCannotSendRequest(err)
+		return &ImageFile{Filename: filepath.Base(path), err: err}
+	}
+	defer file.Close()
+	return ImageFromReader(filepath.Base(path), file)
+}
+
+// ImageFromReader reads an encoded image from r.
+func ImageFromReader(filename string, r io.Reader) *ImageFile {
+	data, err := io.ReadAll(io.LimitReader(r, MaxGPTImageFileSize+1))
+	return &ImageFile{Filename: filename, data: data, err: err}
+}
+
+// ImageFromImage encodes img as PNG.
+func ImageFromImage(filename string, img image.Image) *ImageFile {
+	b := &bytes.Buffer{}
+	err := png.Encode(b, img)
+	return &ImageFile{Filename: filename, data: b.Bytes(), err: err}
+}
+
+// decode checks the file against the images accepted by a model and returns
+// the decoded image. WebP images are not decoded, and return a nil image.
+func (f *ImageFile) decode(input imageInput) (image.Image, error) {
+	if f == nil {
+		return nil, errors.New("image is required")
+	}
+	if f.err != nil {
+		return nil, f.err
 	}
-	if res.StatusCode >= http.StatusBadRequest {
-		return nil, openaiHttpError(res)
+	if len(f.data) > input.maxFileSize {
+		return nil, fmt.Errorf("%s: image must be less than %dMB", f.Filename, input.maxFileSize>>20)
 	}
-	images := new(ImagesGenerationsResponse)
-	if err := goxios.DecodeJSON(res.Body, images); err != nil {
-		return nil, errCannotDecodeJSON(err)
+	contentType := f.contentType()
+	if !contains(input.contentTypes, contentType) {
+		return nil, fmt.Errorf("%s: image must be one of %v, got %s", f.Filename, input.contentTypes, contentType)
+	}
+	var img image.Image
+	var err error
+	switch contentType {
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(f.data))
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(f.data))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: image must be a valid %s file: %w", f.Filename, contentType, err)
+	}
+	bounds := img.Bounds()
+	if input.square && bounds.Dx() != bounds.Dy() {
+		return nil, fmt.Errorf("%s: image must be square, got %dx%d", f.Filename, bounds.Dx(), bounds.Dy())
+	}
+	return img, nil
+}
+
+// contentType returns the format of the file, as sniffed from its content.
+func (f *ImageFile) contentType() string {
+	return http.DetectContentType(f.data)
+}
+
+func (f *ImageFile) formFile(field string) formFile {
+	return formFile{Field: field, Filename: f.Filename, ContentType: f.contentType(), Content: bytes.NewReader(f.data)}
+}
+
+// hasTransparency reports whether img has at least one fully transparent pixel.
+func hasTransparency(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return false
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type (
+	// ImagesEditsRequestBody represents the request body for the image edits API.
+	ImagesEditsRequestBody struct {
+		Image  *ImageFile // The image to edit.
+		Mask   *ImageFile // Optional. Fully transparent areas indicate where Image should be edited.
+		Prompt string
+		Model  string
+		N      int
+		Size   ImageSize
+		// The format in which the generated images are returned, ImageResponseURL or ImageResponseB64.
+		ResponseFormat string
+		User           string
+	}
+
+	// ImagesVariationsRequestBody represents the request body for the image variations API.
+	ImagesVariationsRequestBody struct {
+		Image          *ImageFile
+		Model          string
+		N              int
+		Size           ImageSize
+		ResponseFormat string
+		User           string
+	}
+)
+
+func (body *ImagesEditsRequestBody) validate() error {
+	spec, ok, err := validateImageParams(imageEditsModelSpecs, "edits", body.Model, body.N, body.Size, body.ResponseFormat)
+	if err != nil {
+		return err
+	}
+	input := spec.input
+	if !ok {
+		input = unknownModelImageInput
+	}
+	img, err := body.Image.decode(input)
+	if err != nil {
+		return err
+	}
+	if body.Prompt == "" {
+		return errors.New("prompt is required")
+	}
+	if body.Mask == nil {
+		if input.transparency && !hasTransparency(img) {
+			return errors.New("image must have transparency when no mask is provided")
+		}
+		return nil
+	}
+	mask, err := body.Mask.decode(maskImageInput)
+	if err != nil {
+		return err
+	}
+	// WebP images are not decoded, and their dimensions are left to the API.
+	if img != nil && mask.Bounds().Size() != img.Bounds().Size() {
+		return errors.New("mask must have the same dimensions as image")
+	}
+	if !hasTransparency(mask) {
+		return errors.New("mask must have an alpha channel with fully transparent areas")
+	}
+	return nil
+}
+
+func (body *ImagesEditsRequestBody) form() ([]formField, []formFile) {
+	files := []formFile{body.Image.formFile("image")}
+	if body.Mask != nil {
+		files = append(files, body.Mask.formFile("mask"))
+	}
+	return imageFormFields(body.Model, body.N, body.Size, body.ResponseFormat, body.User, formField{"prompt", body.Prompt}), files
+}
+
+func (body *ImagesVariationsRequestBody) form() ([]formField, []formFile) {
+	return imageFormFields(body.Model, body.N, body.Size, body.ResponseFormat, body.User), []formFile{body.Image.formFile("image")}
+}
+
+func imageFormFields(model string, n int, size ImageSize, responseFormat, user string, fields ...formField) []formField {
+	fields = append(fields, formField{"model", model}, formField{"size", size.String()}, formField{"response_format", responseFormat}, formField{"user", user})
+	if n > 0 {
+		fields = append(fields, formField{"n", strconv.Itoa(n)})
+	}
+	return fields
+}
+
+// ImagesEdits creates edited or extended images given an original image, an optional mask and a prompt.
+func ImagesEdits(api OpenAIClient, httpClient HTTPClient, body *ImagesEditsRequestBody) (*ImagesGenerationsResponse, *OpenAIErr) {
+	if err := body.validate(); err != nil {
+		return nil, errInvalidImage(err)
+	}
+	fields, files := body.form()
+	return imagesMultipart(api, httpClient, "/images/edits", fields, files)
+}
+
+// ImagesVariations creates variations of a given image. Only dall-e-2 supports variations.
+func ImagesVariations(api OpenAIClient, httpClient HTTPClient, body *ImagesVariationsRequestBody) (*ImagesGenerationsResponse, *OpenAIErr) {
+	spec, ok, err := validateImageParams(imageVariationsModelSpecs, "variations", body.Model, body.N, body.Size, body.ResponseFormat)
+	if err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	input := spec.input
+	if !ok {
+		input = unknownModelImageInput
+	}
+	if _, err := body.Image.decode(input); err != nil {
+		return nil, errInvalidImage(err)
+	}
+	fields, files := body.form()
+	return imagesMultipart(api, httpClient, "/images/variations", fields, files)
+}
+
+func imagesMultipart(api OpenAIClient, httpClient HTTPClient, path string, fields []formField, files []formFile) (*ImagesGenerationsResponse, *OpenAIErr) {
+	res, err := postMultipart(api, httpClient, path, multipartForm(fields, files))
+	if err != nil {
+		return nil, err
 	}
-	return images, nil
+	return decodeResponse[ImagesGenerationsResponse](res)
 }
@@ -0,0 +1,135 @@
+package openai
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Simplou/goxios"
+)
+
+// DefaultDownloadConcurrency is the number of images downloaded at the same time when
+// ImageDownloader.Concurrency is not set.
+const DefaultDownloadConcurrency = 4
+
+// ImageDownloader saves generated images, fetching URLs and decoding base64
+// images locally without network access. Downloads run one at a time unless
+// NewHTTPClient is set: HTTPClient is shared and not safe for concurrent use.
+type ImageDownloader struct {
+	HTTPClient HTTPClient
+	// NewHTTPClient gives each worker its own client. It is required for
+	// concurrent downloads.
+	NewHTTPClient HTTPClientFactory
+	// Concurrency is the maximum number of parallel downloads when
+	// NewHTTPClient is set, DefaultDownloadConcurrency by default. It is
+	// ignored without NewHTTPClient.
+	Concurrency int
+}
+
+// Download saves each image of the response to the file path at the same index,
+// fetching them one at a time with httpClient. Use an ImageDownloader with
+// NewHTTPClient to download them concurrently.
+func (igr *ImagesGenerationsResponse) Download(httpClient HTTPClient, filePaths []string) error {
+	downloader := &ImageDownloader{HTTPClient: httpClient}
+	return downloader.Save(igr.Data, filePaths)
+}
+
+// CopyTo writes the image to w. Base64 images are decoded without network access,
+// otherwise the image is fetched from its URL with httpClient.
+func (d ImageData) CopyTo(httpClient HTTPClient, w io.Writer) error {
+	if d.B64JSON != "" {
+		_, err := io.Copy(w, base64.NewDecoder(base64.StdEncoding, strings.NewReader(d.B64JSON)))
+		return err
+	}
+	if d.Url == "" {
+		return errors.New("image has neither url nor b64_json")
+	}
+	if httpClient == nil {
+		return errors.New("an http client is required to download image urls")
+	}
+	res, err := httpClient.Get(d.Url, &goxios.RequestOpts{})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: unexpected status %d", d.Url, res.StatusCode)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.HasPrefix(mediaType, "image/") {
+			return fmt.Errorf("download %s: unexpected content type %q", d.Url, contentType)
+		}
+	}
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// Save writes images[i] to filePaths[i]. Each image is written to a temporary file
+// in the destination directory and renamed once complete, so a failed download
+// never leaves a partial file behind.
+func (dl *ImageDownloader) Save(images []ImageData, filePaths []string) error {
+	if len(filePaths) != len(images) {
+		return errors.New("number of file paths does not match number of images")
+	}
+	return dl.each(images, func(httpClient HTTPClient, i int) error {
+		return saveImage(httpClient, images[i], filePaths[i])
+	})
+}
+
+// Write writes images[i] to writers[i].
+func (dl *ImageDownloader) Write(images []ImageData, writers []io.Writer) error {
+	if len(writers) != len(images) {
+		return errors.New("number of writers does not match number of images")
+	}
+	return dl.each(images, func(httpClient HTTPClient, i int) error {
+		return images[i].CopyTo(httpClient, writers[i])
+	})
+}
+
+// each runs fn for every image, one at a time unless dl.NewHTTPClient is set,
+// with at most dl.Concurrency workers then. Once an image fails no new images
+// are started, and the errors of all failed images are returned.
+func (dl *ImageDownloader) each(images []ImageData, fn func(httpClient HTTPClient, i int) error) error {
+	concurrency := dl.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDownloadConcurrency
+	}
+	concurrency = dl.NewHTTPClient.concurrency(concurrency)
+	errs := parallel(len(images), concurrency, dl.NewHTTPClient, dl.HTTPClient, func(httpClient HTTPClient, i int) error {
+		if err := fn(httpClient, i); err != nil {
+			return fmt.Errorf("image %d: %w", i, err)
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+func saveImage(httpClient HTTPClient, image ImageData, filePath string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = image.CopyTo(httpClient, tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
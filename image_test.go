@@ -0,0 +1,471 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockImagesHTTPClient records the multipart parts it receives.
+type MockImagesHTTPClient struct {
+	url   string
+	parts map[string]string
+}
+
+func (c *MockImagesHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockImagesHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	c.url = url
+	c.parts = map[string]string{}
+	var contentType string
+	for _, h := range opts.Headers {
+		if h.Key == "Content-Type" {
+			contentType = h.Value.(string)
+		}
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(opts.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		c.parts[part.FormName()] = string(b)
+	}
+	json := goxios.JSON{"created": 1, "data": []goxios.JSON{{"url": "https://example.com/image.png"}}}
+	b, err := json.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(ioReader(b))}, nil
+}
+
+// headerClient keeps the headers added by the endpoints so the mock can read them.
+type headerClient struct {
+	MockClient
+	headers []goxios.Header
+}
+
+func (c *headerClient) AddHeader(h goxios.Header) { c.headers = append(c.headers, h) }
+
+func (c *headerClient) Headers() []goxios.Header { return c.headers }
+
+func testImage(w, h int, transparent bool) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	if transparent {
+		img.Set(0, 0, color.NRGBA{})
+	}
+	return img
+}
+
+// testJPEG encodes img as JPEG.
+func testJPEG(img image.Image) io.Reader {
+	b := &bytes.Buffer{}
+	jpeg.Encode(b, img, nil)
+	return b
+}
+
+func TestImagesEdits(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        *ImagesEditsRequestBody
+		expectedErr bool
+	}{
+		{
+			name: "Image with mask",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, false)),
+				Mask:   ImageFromImage("mask.png", testImage(4, 4, true)),
+				Prompt: "a robot",
+			},
+		},
+		{
+			name: "Transparent image without mask",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, true)),
+				Prompt: "a robot",
+			},
+		},
+		{
+			name: "Opaque image without mask",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, false)),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Mask without transparency",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, false)),
+				Mask:   ImageFromImage("mask.png", testImage(4, 4, false)),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Mask with different dimensions",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, false)),
+				Mask:   ImageFromImage("mask.png", testImage(2, 2, true)),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Image is not a PNG",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromReader("image.png", strings.NewReader("not a png")),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "dall-e-3 does not edit images",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 4, true)),
+				Prompt: "a robot",
+				Model:  DallE3ImageModel,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Image is not square",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(4, 2, true)),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "dall-e-2 does not edit JPEG images",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromReader("image.jpg", testJPEG(testImage(4, 4, false))),
+				Prompt: "a robot",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "gpt-image-1 edits a non-square opaque image",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromImage("image.png", testImage(6, 4, false)),
+				Prompt: "a robot",
+				Model:  GPTImage1Model,
+			},
+		},
+		{
+			name: "gpt-image-1 edits a JPEG image with a mask",
+			body: &ImagesEditsRequestBody{
+				Image:  ImageFromReader("image.jpg", testJPEG(testImage(6, 4, false))),
+				Mask:   ImageFromImage("mask.png", testImage(6, 4, true)),
+				Prompt: "a robot",
+				Model:  GPTImage1Model,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &headerClient{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+			httpClient := new(MockImagesHTTPClient)
+			res, err := ImagesEdits(client, httpClient, tc.body)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, but got nil")
+				}
+				if err.Status() != http.StatusBadRequest {
+					t.Errorf("Expected status %d, got %d", http.StatusBadRequest, err.Status())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if httpClient.url != client.BaseURL()+"/images/edits" {
+				t.Errorf("Unexpected url: %s", httpClient.url)
+			}
+			if httpClient.parts["prompt"] != "a robot" {
+				t.Errorf("Unexpected prompt: %q", httpClient.parts["prompt"])
+			}
+			if _, ok := httpClient.parts["mask"]; ok != (tc.body.Mask != nil) {
+				t.Errorf("Unexpected mask part presence: %v", ok)
+			}
+			if len(res.Data) != 1 {
+				t.Errorf("Expected 1 image, got %d", len(res.Data))
+			}
+		})
+	}
+}
+
+func TestImagesVariations(t *testing.T) {
+	client := &headerClient{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	httpClient := new(MockImagesHTTPClient)
+	res, err := ImagesVariations(client, httpClient, &ImagesVariationsRequestBody{
+		Image: ImageFromImage("image.png", testImage(4, 4, false)),
+		N:     2,
+		Size:  DallE2Size512x512,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if httpClient.url != client.BaseURL()+"/images/variations" {
+		t.Errorf("Unexpected url: %s", httpClient.url)
+	}
+	if httpClient.parts["n"] != "2" {
+		t.Errorf("Unexpected n: %q", httpClient.parts["n"])
+	}
+	if httpClient.parts["size"] != "512x512" {
+		t.Errorf("Unexpected size: %q", httpClient.parts["size"])
+	}
+	if len(res.Data) != 1 {
+		t.Errorf("Expected 1 image, got %d", len(res.Data))
+	}
+
+	for _, model := range []string{DallE3ImageModel, GPTImage1Model} {
+		_, err := ImagesVariations(client, httpClient, &ImagesVariationsRequestBody{
+			Image: ImageFromImage("image.png", testImage(4, 4, false)),
+			Model: model,
+		})
+		if err == nil || err.Status() != http.StatusBadRequest {
+			t.Errorf("Expected %s variations to be rejected, got %v", model, err)
+		}
+	}
+}
+
+func TestImagesGenerationsValidation(t *testing.T) {
+	compression := 150
+	testCases := []struct {
+		name        string
+		body        *ImagesGenerationsRequestBody
+		expectedErr bool
+	}{
+		{
+			name: "dall-e-3 with one image",
+			body: &ImagesGenerationsRequestBody{Model: DallE3ImageModel, Prompt: "a gopher", N: 1, Size: DallE3Size1792x1024, Quality: "hd", Style: VividImageStyle().String()},
+		},
+		{
+			name:        "dall-e-3 with many images",
+			body:        &ImagesGenerationsRequestBody{Model: DallE3ImageModel, Prompt: "a gopher", N: 2},
+			expectedErr: true,
+		},
+		{
+			name:        "dall-e-2 with dall-e-3 size",
+			body:        &ImagesGenerationsRequestBody{Model: DallE2ImageModel, Prompt: "a gopher", Size: DallE3Size1024x1792},
+			expectedErr: true,
+		},
+		{
+			name:        "dall-e-2 with style",
+			body:        &ImagesGenerationsRequestBody{Prompt: "a gopher", Style: NaturalImageStyle().String()},
+			expectedErr: true,
+		},
+		{
+			name:        "gpt-image-1 with response format",
+			body:        &ImagesGenerationsRequestBody{Model: GPTImage1Model, Prompt: "a gopher", ResponseFormat: ImageResponseURL},
+			expectedErr: true,
+		},
+		{
+			name:        "gpt-image-1 with invalid compression",
+			body:        &ImagesGenerationsRequestBody{Model: GPTImage1Model, Prompt: "a gopher", OutputFormat: "webp", OutputCompression: &compression},
+			expectedErr: true,
+		},
+		{
+			name: "Unknown model",
+			body: &ImagesGenerationsRequestBody{Model: "future-image-model", Prompt: "a gopher", N: 20, Size: "42x42"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.body.validate()
+			if tc.expectedErr && err == nil {
+				t.Errorf("Expected an error, but got nil")
+			}
+			if !tc.expectedErr && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+// MockDownloadHTTPClient serves images for the download tests.
+type MockDownloadHTTPClient struct {
+	statusCode  int
+	contentType string
+}
+
+func (c *MockDownloadHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockDownloadHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Content-Type", c.contentType)
+	return &http.Response{
+		StatusCode: c.statusCode,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("image from " + url)),
+	}, nil
+}
+
+func TestImagesDownload(t *testing.T) {
+	testCases := []struct {
+		name        string
+		httpClient  *MockDownloadHTTPClient
+		data        []ImageData
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:       "Urls",
+			httpClient: &MockDownloadHTTPClient{http.StatusOK, "image/png"},
+			data:       []ImageData{{Url: "a"}, {Url: "b"}, {Url: "c"}},
+			expected:   []string{"image from a", "image from b", "image from c"},
+		},
+		{
+			name:     "Base64 without network",
+			data:     []ImageData{{B64JSON: base64.StdEncoding.EncodeToString([]byte("decoded"))}},
+			expected: []string{"decoded"},
+		},
+		{
+			name:        "Error status",
+			httpClient:  &MockDownloadHTTPClient{http.StatusForbidden, "image/png"},
+			data:        []ImageData{{Url: "a"}},
+			expectedErr: true,
+		},
+		{
+			name:        "Error page",
+			httpClient:  &MockDownloadHTTPClient{http.StatusOK, "text/html"},
+			data:        []ImageData{{Url: "a"}},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePaths := make([]string, len(tc.data))
+			for i := range filePaths {
+				filePaths[i] = filepath.Join(dir, fmt.Sprintf("%d.png", i))
+			}
+			res := &ImagesGenerationsResponse{Data: tc.data}
+			var httpClient HTTPClient
+			if tc.httpClient != nil {
+				httpClient = tc.httpClient
+			}
+			err := res.Download(httpClient, filePaths)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, but got nil")
+				}
+				entries, _ := os.ReadDir(dir)
+				if len(entries) != 0 {
+					t.Errorf("Expected no files after a failed download, got %d", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			for i, filePath := range filePaths {
+				b, err := os.ReadFile(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != tc.expected[i] {
+					t.Errorf("Expected %q, got %q", tc.expected[i], b)
+				}
+			}
+		})
+	}
+}
+
+// concurrencyServer records the highest number of requests it served at the
+// same time, to check that a single HTTPClient is never shared by workers.
+type concurrencyServer struct {
+	*httptest.Server
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func newConcurrencyServer(handler http.HandlerFunc) *concurrencyServer {
+	s := &concurrencyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for m := s.maxInFlight.Load(); n > m && !s.maxInFlight.CompareAndSwap(m, n); m = s.maxInFlight.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		handler(w, r)
+	}))
+	return s
+}
+
+// TestImagesDownloadSharedClient downloads with a goxios client, which keeps the
+// state of its request and must not be used by several workers; go test -race
+// reports it otherwise.
+func TestImagesDownloadSharedClient(t *testing.T) {
+	server := newConcurrencyServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "image from "+r.URL.Path)
+	})
+	defer server.Close()
+	data := make([]ImageData, 6)
+	filePaths := make([]string, len(data))
+	dir := t.TempDir()
+	for i := range data {
+		data[i].Url = fmt.Sprintf("%s/%d", server.URL, i)
+		filePaths[i] = filepath.Join(dir, fmt.Sprintf("%d.png", i))
+	}
+	res := &ImagesGenerationsResponse{Data: data}
+	if err := res.Download(goxios.New(context.Background()), filePaths); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if n := server.maxInFlight.Load(); n != 1 {
+		t.Errorf("Expected the shared client to download one image at a time, got %d", n)
+	}
+	for i, filePath := range filePaths {
+		if b, _ := os.ReadFile(filePath); string(b) != fmt.Sprintf("image from /%d", i) {
+			t.Errorf("Unexpected image %d: %q", i, b)
+		}
+	}
+
+	server.maxInFlight.Store(0)
+	downloader := &ImageDownloader{NewHTTPClient: func() HTTPClient { return goxios.New(context.Background()) }, Concurrency: 3}
+	if err := downloader.Save(data, filePaths); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if n := server.maxInFlight.Load(); n > 3 {
+		t.Errorf("Expected at most 3 parallel downloads, got %d", n)
+	}
+}
@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"net/url"
+
+	"github.com/Simplou/goxios"
+)
+
+type (
+	// List is a page of the resources returned by a list endpoint.
+	List[T any] struct {
+		Object  string `json:"object"`
+		Data    []T    `json:"data"`
+		FirstId string `json:"first_id,omitempty"`
+		LastId  string `json:"last_id,omitempty"`
+		HasMore bool   `json:"has_more"`
+	}
+
+	// ListParams paginates list endpoints. Zero values use the API defaults.
+	ListParams struct {
+		Limit int
+		// Order by creation date, asc or desc.
+		Order string
+		// After and Before are resource ids used as cursors.
+		After, Before string
+	}
+
+	// Deletion is the response of the endpoints that delete resources.
+	Deletion struct {
+		Id      string `json:"id"`
+		Object  string `json:"object"`
+		Deleted bool   `json:"deleted"`
+	}
+)
+
+func (p *ListParams) queryParams() []goxios.QueryParam {
+	if p == nil {
+		return nil
+	}
+	var params []goxios.QueryParam
+	if p.Limit > 0 {
+		params = append(params, goxios.QueryParam{Key: "limit", Value: p.Limit})
+	}
+	for _, param := range []goxios.QueryParam{{Key: "order", Value: p.Order}, {Key: "after", Value: p.After}, {Key: "before", Value: p.Before}} {
+		if param.Value != "" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// listAll fetches the pages of a list endpoint until the last one, passing the
+// id of the last resource received as the after cursor of the next page.
+func listAll[T any](after string, id func(T) string, fetch func(after string) (*List[T], *OpenAIErr)) ([]T, *OpenAIErr) {
+	var all []T
+	for {
+		page, err := fetch(after)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Data...)
+		if !page.HasMore || len(page.Data) == 0 {
+			return all, nil
+		}
+		after = id(page.Data[len(page.Data)-1])
+	}
+}
+
+// pathID escapes a resource id to be used in a request path.
+func pathID(id string) string {
+	return "/" + url.PathEscape(id)
+}
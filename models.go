@@ -0,0 +1,181 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Default models used when a request does not set one.
+const (
+	DefaultChatModel      = "gpt-4o-mini"
+	DefaultEmbeddingModel = "text-embedding-3-small"
+	DefaultRealtimeModel  = "gpt-4o-realtime-preview"
+)
+
+type (
+	// Model is a model available to the API key.
+	Model struct {
+		Id      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	}
+
+	// ModelCapabilities describes what a model accepts. Zero limits are unknown.
+	ModelCapabilities struct {
+		// ContextWindow is the maximum number of input and output tokens.
+		ContextWindow   int
+		MaxOutputTokens int
+		Vision          bool
+		Audio           bool
+		Tools           bool
+		// EmbeddingDimensions is the size of the embeddings of embedding models.
+		EmbeddingDimensions int
+		// ReducibleDimensions tells whether embeddings can be shortened with the
+		// dimensions parameter.
+		ReducibleDimensions bool
+		// FineTuningPrice is the price of fine-tuning the model in USD per million
+		// training tokens. It is zero for the models that cannot be fine-tuned.
+		FineTuningPrice float64
+		// TextCompletions tells whether the model completes raw prompts with the
+		// legacy completions endpoint instead of conversations.
+		TextCompletions bool
+	}
+)
+
+var (
+	modelCapabilitiesMu sync.RWMutex
+	modelCapabilities   = map[string]ModelCapabilities{
+		"gpt-3.5-turbo":             {ContextWindow: 16385, MaxOutputTokens: 4096, Tools: true, FineTuningPrice: 8},
+		"gpt-3.5-turbo-instruct":    {ContextWindow: 4096, MaxOutputTokens: 4096, TextCompletions: true},
+		"davinci-002":               {ContextWindow: 16384, MaxOutputTokens: 16384, TextCompletions: true, FineTuningPrice: 6},
+		"babbage-002":               {ContextWindow: 16384, MaxOutputTokens: 16384, TextCompletions: true, FineTuningPrice: 0.4},
+		"gpt-4":                     {ContextWindow: 8192, MaxOutputTokens: 8192, Tools: true},
+		"gpt-4-turbo":               {ContextWindow: 128000, MaxOutputTokens: 4096, Vision: true, Tools: true},
+		"gpt-4o":                    {ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, FineTuningPrice: 25},
+		"gpt-4o-mini":               {ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, FineTuningPrice: 3},
+		"gpt-4o-audio-preview":      {ContextWindow: 128000, MaxOutputTokens: 16384, Audio: true, Tools: true},
+		"gpt-4o-mini-audio-preview": {ContextWindow: 128000, MaxOutputTokens: 16384, Audio: true, Tools: true},
+		"gpt-4.1":                   {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, FineTuningPrice: 25},
+		"gpt-4.1-mini":              {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, FineTuningPrice: 5},
+		"gpt-4.1-nano":              {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, FineTuningPrice: 1.5},
+		"gpt-5":                     {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true},
+		"gpt-5-mini":                {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true},
+		"gpt-5-nano":                {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true},
+		"o1":                        {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true},
+		"o1-mini":                   {ContextWindow: 128000, MaxOutputTokens: 65536},
+		"o3":                        {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true},
+		"o3-mini":                   {ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true},
+		"o4-mini":                   {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true},
+		"text-embedding-ada-002":    {ContextWindow: 8191, EmbeddingDimensions: 1536},
+		"text-embedding-3-small":    {ContextWindow: 8191, EmbeddingDimensions: 1536, ReducibleDimensions: true},
+		"text-embedding-3-large":    {ContextWindow: 8191, EmbeddingDimensions: 3072, ReducibleDimensions: true},
+	}
+)
+
+// RegisterModel adds or replaces the capabilities of a model, such as a model
+// released after this version of the package.
+func RegisterModel(model string, capabilities ModelCapabilities) {
+	modelCapabilitiesMu.Lock()
+	defer modelCapabilitiesMu.Unlock()
+	modelCapabilities[model] = capabilities
+}
+
+// snapshotSuffix matches the date of a model snapshot, such as -2024-08-06, or
+// -0613 for the older models.
+var snapshotSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}|\d{4})$`)
+
+// LookupModel returns the capabilities of a model. Snapshots such as
+// gpt-4o-2024-08-06 and fine-tuned models such as ft:gpt-4o-mini:org::id
+// resolve to their base model. Other models, such as gpt-4-vision-preview, are
+// unknown even when their name starts with a known one.
+func LookupModel(model string) (ModelCapabilities, bool) {
+	if base, ok := strings.CutPrefix(model, "ft:"); ok {
+		model, _, _ = strings.Cut(base, ":")
+	}
+	modelCapabilitiesMu.RLock()
+	defer modelCapabilitiesMu.RUnlock()
+	if capabilities, ok := modelCapabilities[model]; ok {
+		return capabilities, true
+	}
+	capabilities, ok := modelCapabilities[snapshotSuffix.ReplaceAllString(model, "")]
+	return capabilities, ok
+}
+
+// Capabilities returns the capabilities of the model. See LookupModel.
+func (m *Model) Capabilities() (ModelCapabilities, bool) {
+	return LookupModel(m.Id)
+}
+
+// validateChatModel checks the request against the capabilities of its model.
+// Unknown models are not checked.
+func validateChatModel(model string, messages any, tools []Tool) error {
+	capabilities, ok := LookupModel(model)
+	if !ok {
+		return nil
+	}
+	if capabilities.EmbeddingDimensions > 0 {
+		return fmt.Errorf("%s is an embedding model", model)
+	}
+	if capabilities.TextCompletions {
+		return fmt.Errorf("%s is not a chat model, use TextCompletion", model)
+	}
+	if len(tools) > 0 && !capabilities.Tools {
+		return fmt.Errorf("%s does not support tools", model)
+	}
+	if messages, ok := messages.(MediaMessages); ok && !capabilities.Vision {
+		for _, message := range messages {
+			for _, part := range message.Content {
+				if part.Type == "image_url" {
+					return fmt.Errorf("%s does not support image inputs", model)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateEmbeddingModel checks the dimensions requested from an embedding
+// model. Unknown models are not checked.
+func validateEmbeddingModel(model string, dimensions int) error {
+	capabilities, ok := LookupModel(model)
+	if !ok {
+		return nil
+	}
+	if capabilities.EmbeddingDimensions == 0 {
+		return fmt.Errorf("%s is not an embedding model", model)
+	}
+	if dimensions == 0 {
+		return nil
+	}
+	if !capabilities.ReducibleDimensions {
+		return fmt.Errorf("%s does not support the dimensions parameter", model)
+	}
+	if dimensions < 0 || dimensions > capabilities.EmbeddingDimensions {
+		return fmt.Errorf("%s supports at most %d dimensions, got %d", model, capabilities.EmbeddingDimensions, dimensions)
+	}
+	return nil
+}
+
+// ListModels lists the models available to the API key.
+func ListModels(api OpenAIClient, httpClient HTTPClient) (*List[Model], *OpenAIErr) {
+	return getJSON[List[Model]](api, httpClient, "/models")
+}
+
+func RetrieveModel(api OpenAIClient, httpClient HTTPClient, model string) (*Model, *OpenAIErr) {
+	if model == "" {
+		return nil, errInvalidRequest(errors.New("model is required"))
+	}
+	return getJSON[Model](api, httpClient, "/models"+pathID(model))
+}
+
+// DeleteModel deletes a fine-tuned model. The organization must own it.
+func DeleteModel(api OpenAIClient, httpClient HTTPDeleteClient, model string) (*Deletion, *OpenAIErr) {
+	if model == "" {
+		return nil, errInvalidRequest(errors.New("model is required"))
+	}
+	return deleteJSON[Deletion](api, httpClient, "/models"+pathID(model))
+}
@@ -0,0 +1,133 @@
+package openai
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model         string
+		ok            bool
+		contextWindow int
+		vision        bool
+	}{
+		{"gpt-4o", true, 128000, true},
+		{"gpt-4o-mini-2024-07-18", true, 128000, true},
+		{"gpt-4-0613", true, 8192, false},
+		{"gpt-4.1-nano-2025-04-14", true, 1047576, true},
+		{"ft:gpt-3.5-turbo-0125:my-org:custom:abc123", true, 16385, false},
+		{"gpt-4omega", false, 0, false},
+		{"gpt-4-vision-preview", false, 0, false},
+		{"o1-preview", false, 0, false},
+		{"gpt-4o-realtime-preview", false, 0, false},
+		{"gpt-4o-mini-search-preview", false, 0, false},
+		{"my-model", false, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.model, func(t *testing.T) {
+			capabilities, ok := LookupModel(test.model)
+			if ok != test.ok || capabilities.ContextWindow != test.contextWindow || capabilities.Vision != test.vision {
+				t.Errorf("unexpected capabilities %+v, %t", capabilities, ok)
+			}
+		})
+	}
+
+	RegisterModel("my-model", ModelCapabilities{ContextWindow: 32000, Tools: true})
+	if capabilities, ok := (&Model{Id: "my-model-2025-01-31"}).Capabilities(); !ok || capabilities.ContextWindow != 32000 {
+		t.Errorf("expected the registered model, got %+v, %t", capabilities, ok)
+	}
+}
+
+func TestModelValidation(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	tool := Tool{Type: "function", Function: Function{Name: "f"}}
+	image := MediaMessages{{Role: "user", Content: []MediaMessage{ImageContent(ImageUrl("https://example.com/a.png"))}}}
+	tests := map[string]func(HTTPClient) *OpenAIErr{
+		"ToolsUnsupported": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := ChatCompletion(api, httpClient, &CompletionRequest[DefaultMessages]{Model: "o1-mini", Tools: []Tool{tool}})
+			return err
+		},
+		"VisionUnsupported": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := ChatCompletion(api, httpClient, &CompletionRequest[MediaMessages]{Model: "gpt-3.5-turbo", Messages: image})
+			return err
+		},
+		"EmbeddingModelInChat": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := ChatCompletion(api, httpClient, &CompletionRequest[DefaultMessages]{Model: "text-embedding-3-small"})
+			return err
+		},
+		"TooManyDimensions": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := CreateEmbedding[string, []float64](api, httpClient, &EmbeddingRequest[string]{Model: "text-embedding-3-small", Input: "Hi", Dimensions: 2048})
+			return err
+		},
+		"DimensionsUnsupported": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := CreateEmbedding[string, []float64](api, httpClient, &EmbeddingRequest[string]{Model: "text-embedding-ada-002", Input: "Hi", Dimensions: 512})
+			return err
+		},
+		"ChatModelInEmbeddings": func(httpClient HTTPClient) *OpenAIErr {
+			_, err := CreateEmbedding[string, []float64](api, httpClient, &EmbeddingRequest[string]{Model: "gpt-4o", Input: "Hi"})
+			return err
+		},
+	}
+	for name, call := range tests {
+		t.Run(name, func(t *testing.T) {
+			httpClient := &MockModerationHTTPClient{}
+			err := call(httpClient)
+			if err == nil || err.Status() != http.StatusBadRequest {
+				t.Fatalf("expected an invalid request error, got %v", err)
+			}
+			if httpClient.body != nil {
+				t.Error("invalid request should not be sent")
+			}
+		})
+	}
+}
+
+func TestUnknownModelNotValidated(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockModerationHTTPClient{}
+	image := MediaMessages{{Role: "user", Content: []MediaMessage{ImageContent(ImageUrl("https://example.com/a.png"))}}}
+	if _, err := ChatCompletion(api, httpClient, &CompletionRequest[MediaMessages]{Model: "gpt-4-vision-preview", Messages: image}); err != nil && err.Status() == http.StatusBadRequest {
+		t.Fatalf("expected the image input to be sent to gpt-4-vision-preview, got %v", err)
+	}
+	if httpClient.body == nil {
+		t.Error("expected the request to be sent")
+	}
+}
+
+func TestDefaultModels(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockModerationHTTPClient{}
+	body := &CompletionRequest[DefaultMessages]{Messages: DefaultMessages{{Role: "user", Content: "Hi"}}}
+	ChatCompletion(api, httpClient, body)
+	if body.Model != "" {
+		t.Error("the request body should not be modified")
+	}
+	if want := `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hi"}]}`; string(httpClient.body) != want {
+		t.Errorf("expected %s, got %s", want, httpClient.body)
+	}
+	CreateEmbedding[string, []float64](api, httpClient, &EmbeddingRequest[string]{Input: "Hi"})
+	if want := `{"input":"Hi","model":"text-embedding-3-small"}`; string(httpClient.body) != want {
+		t.Errorf("expected %s, got %s", want, httpClient.body)
+	}
+}
+
+func TestModels(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockResponseHTTPClient{statusCode: http.StatusOK, body: `{"object":"list","data":[{"id":"gpt-4o","object":"model","created":1715367049,"owned_by":"system"}]}`}
+	models, err := ListModels(api, httpClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(models.Data) != 1 || models.Data[0].Id != "gpt-4o" || models.Data[0].OwnedBy != "system" {
+		t.Errorf("unexpected models: %+v", models)
+	}
+	if capabilities, ok := models.Data[0].Capabilities(); !ok || !capabilities.Vision {
+		t.Errorf("unexpected capabilities: %+v", capabilities)
+	}
+	httpClient.body = `{"id":"ft:gpt-4o-mini:org::1","object":"model","deleted":true}`
+	deletion, err := DeleteModel(api, httpClient, "ft:gpt-4o-mini:org::1")
+	if err != nil || !deletion.Deleted {
+		t.Errorf("unexpected deletion %+v, error %v", deletion, err)
+	}
+}
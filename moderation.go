@@ -1,48 +1,192 @@
 package openai
 
 import (
-	"encoding/json"
+	"fmt"
+	"strings"
+)
 
-	"github.com/Simplou/goxios"
+// Moderation categories.
+const (
+	ModerationHarassment            = "harassment"
+	ModerationHarassmentThreatening = "harassment/threatening"
+	ModerationHate                  = "hate"
+	ModerationHateThreatening       = "hate/threatening"
+	ModerationIllicit               = "illicit"
+	ModerationIllicitViolent        = "illicit/violent"
+	ModerationSelfHarm              = "self-harm"
+	ModerationSelfHarmIntent        = "self-harm/intent"
+	ModerationSelfHarmInstructions  = "self-harm/instructions"
+	ModerationSexual                = "sexual"
+	ModerationSexualMinors          = "sexual/minors"
+	ModerationViolence              = "violence"
+	ModerationViolenceGraphic       = "violence/graphic"
 )
 
+// ModerationInput is the input of a moderation request: one text, several texts,
+// or text and image parts built with TextContent and ImageContent. Images are
+// only supported by the omni-moderation models.
+type ModerationInput interface {
+	string | []string | []MediaMessage
+}
+
 type (
-	ModerationRequest[Input string | []string] struct {
+	ModerationRequest[Input ModerationInput] struct {
 		Input Input  `json:"input"`
 		Model string `json:"model,omitempty"`
 	}
 
 	ModerationResponse struct {
-		Id      string `json:"id"`
-		Model   string `json:"model"`
-		Results []struct {
-			Flagged    bool                     `json:"flagged"`
-			Categories goxios.GenericJSON[bool] `json:"categories"`
-		} `json:"results"`
-		CategoryScores goxios.GenericJSON[float64] `json:"category_scores"`
+		Id      string             `json:"id"`
+		Model   string             `json:"model"`
+		Results []ModerationResult `json:"results"`
+	}
+
+	// ModerationResult is the moderation of one input.
+	ModerationResult struct {
+		Flagged        bool                          `json:"flagged"`
+		Categories     ModerationCategories[bool]    `json:"categories"`
+		CategoryScores ModerationCategories[float64] `json:"category_scores"`
+		// CategoryAppliedInputTypes lists, for each category, the input types (text or image)
+		// that were scored. Only returned by omni-moderation models.
+		CategoryAppliedInputTypes ModerationCategories[[]string] `json:"category_applied_input_types,omitempty"`
+	}
+
+	// ModerationCategories holds a value of type T for each moderation category.
+	ModerationCategories[T any] struct {
+		Harassment            T `json:"harassment"`
+		HarassmentThreatening T `json:"harassment/threatening"`
+		Hate                  T `json:"hate"`
+		HateThreatening       T `json:"hate/threatening"`
+		Illicit               T `json:"illicit"`
+		IllicitViolent        T `json:"illicit/violent"`
+		SelfHarm              T `json:"self-harm"`
+		SelfHarmIntent        T `json:"self-harm/intent"`
+		SelfHarmInstructions  T `json:"self-harm/instructions"`
+		Sexual                T `json:"sexual"`
+		SexualMinors          T `json:"sexual/minors"`
+		Violence              T `json:"violence"`
+		ViolenceGraphic       T `json:"violence/graphic"`
 	}
+
+	// ModerationThresholds maps categories to the score from which they are flagged.
+	ModerationThresholds map[string]float64
 )
 
-func Moderator[Input string | []string](api OpenAIClient, httpClient HTTPClient, body *ModerationRequest[Input]) (*ModerationResponse, *OpenAIErr) {
-	api.AddHeader(contentTypeJSON)
-	b, err := json.Marshal(body)
-	if err != nil {
-		return nil, errCannotMarshalJSON(err)
+// Map returns the values of the categories by category name.
+func (c ModerationCategories[T]) Map() map[string]T {
+	return map[string]T{
+		ModerationHarassment:            c.Harassment,
+		ModerationHarassmentThreatening: c.HarassmentThreatening,
+		ModerationHate:                  c.Hate,
+		ModerationHateThreatening:       c.HateThreatening,
+		ModerationIllicit:               c.Illicit,
+		ModerationIllicitViolent:        c.IllicitViolent,
+		ModerationSelfHarm:              c.SelfHarm,
+		ModerationSelfHarmIntent:        c.SelfHarmIntent,
+		ModerationSelfHarmInstructions:  c.SelfHarmInstructions,
+		ModerationSexual:                c.Sexual,
+		ModerationSexualMinors:          c.SexualMinors,
+		ModerationViolence:              c.Violence,
+		ModerationViolenceGraphic:       c.ViolenceGraphic,
 	}
-	options := goxios.RequestOpts{
-		Body:    ioReader(b),
-		Headers: api.Headers(),
+}
+
+// moderationCategories lists the categories in a stable order.
+var moderationCategories = []string{
+	ModerationHarassment, ModerationHarassmentThreatening, ModerationHate, ModerationHateThreatening,
+	ModerationIllicit, ModerationIllicitViolent, ModerationSelfHarm, ModerationSelfHarmIntent,
+	ModerationSelfHarmInstructions, ModerationSexual, ModerationSexualMinors, ModerationViolence,
+	ModerationViolenceGraphic,
+}
+
+// FlaggedCategories returns the categories flagged by the API.
+func (r ModerationResult) FlaggedCategories() []string {
+	flags := r.Categories.Map()
+	var categories []string
+	for _, category := range moderationCategories {
+		if flags[category] {
+			categories = append(categories, category)
+		}
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/moderations", &options)
-	if err != nil {
-		return nil, errCannotSendRequest(err)
+	return categories
+}
+
+// CategoriesOver returns the categories whose score reaches their threshold.
+// Categories without a threshold are returned when flagged by the API.
+func (r ModerationResult) CategoriesOver(thresholds ModerationThresholds) []string {
+	flags, scores := r.Categories.Map(), r.CategoryScores.Map()
+	var categories []string
+	for _, category := range moderationCategories {
+		threshold, ok := thresholds[category]
+		if ok && scores[category] >= threshold || !ok && flags[category] {
+			categories = append(categories, category)
+		}
 	}
-	response := new(ModerationResponse)
-	if err := goxios.DecodeJSON(res.Body, response); err != nil {
-		return nil, errCannotDecodeJSON(err)
+	return categories
+}
+
+// Flagged reports whether any input was flagged by the API.
+func (mr *ModerationResponse) Flagged() bool {
+	for _, result := range mr.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// FlaggedCategories returns the categories flagged in any of the inputs.
+func (mr *ModerationResponse) FlaggedCategories() []string {
+	return mr.CategoriesOver(nil)
+}
+
+// CategoriesOver returns the categories over their threshold in any of the inputs.
+// See ModerationResult.CategoriesOver.
+func (mr *ModerationResponse) CategoriesOver(thresholds ModerationThresholds) []string {
+	over := map[string]bool{}
+	for _, result := range mr.Results {
+		for _, category := range result.CategoriesOver(thresholds) {
+			over[category] = true
+		}
+	}
+	var categories []string
+	for _, category := range moderationCategories {
+		if over[category] {
+			categories = append(categories, category)
+		}
 	}
-	if err := res.Body.Close(); err != nil {
-		return nil, errCloseBody(err)
+	return categories
+}
+
+func (r *ModerationRequest[Input]) validate() error {
+	parts, ok := any(r.Input).([]MediaMessage)
+	if !ok {
+		return nil
+	}
+	for i, part := range parts {
+		switch part.Type {
+		case "text":
+		case "image_url":
+			if part.ImageUrl == nil || part.ImageUrl.Url == "" {
+				return fmt.Errorf("input %d: image_url is required", i)
+			}
+			if r.Model != "" && !strings.HasPrefix(r.Model, "omni-moderation") {
+				return fmt.Errorf("%s does not support image inputs", r.Model)
+			}
+		default:
+			return fmt.Errorf("input %d: unsupported type %q", i, part.Type)
+		}
+	}
+	return nil
+}
+
+func Moderator[Input ModerationInput](api OpenAIClient, httpClient HTTPClient, body *ModerationRequest[Input]) (*ModerationResponse, *OpenAIErr) {
+	if err := body.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	res, err := postJSON(api, httpClient, "/moderations", body)
+	if err != nil {
+		return nil, err
 	}
-	return response, nil
+	return decodeResponse[ModerationResponse](res)
 }
@@ -0,0 +1,221 @@
+package openai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModerationPolicy is what a ModerationGuard does with flagged content.
+type ModerationPolicy string
+
+const (
+	// ModerationBlock fails the call with a *ModerationError.
+	ModerationBlock ModerationPolicy = "block"
+	// ModerationRedact replaces flagged messages with ModerationGuard.Redaction.
+	ModerationRedact ModerationPolicy = "redact"
+	// ModerationWarn only reports flagged content to ModerationGuard.OnFlagged.
+	ModerationWarn ModerationPolicy = "warn"
+)
+
+// ModerationStage tells whether the input or the output of a chat was flagged.
+type ModerationStage string
+
+const (
+	ModerationStageInput  ModerationStage = "input"
+	ModerationStageOutput ModerationStage = "output"
+)
+
+// DefaultRedaction replaces flagged content when ModerationGuard.Redaction is empty.
+const DefaultRedaction = "[redacted]"
+
+// ModerationGuard configures ModeratedChatCompletion.
+type ModerationGuard struct {
+	// Model is the moderation model. The API default is used when empty.
+	Model string
+	// Thresholds overrides the API flags for the given categories.
+	// See ModerationResult.CategoriesOver.
+	Thresholds ModerationThresholds
+	// Policy defaults to ModerationBlock.
+	Policy ModerationPolicy
+	// ModerateInput moderates the user messages before they are sent, and
+	// ModerateOutput the choices returned by the model. When neither is set,
+	// only the input is moderated.
+	ModerateInput  bool
+	ModerateOutput bool
+	// Redaction replaces flagged content under ModerationRedact.
+	Redaction string
+	// OnFlagged, when set, is called with every flagged input or output,
+	// whatever the policy.
+	OnFlagged func(*ModerationError)
+}
+
+// ModerationError describes content flagged by a ModerationGuard. Under
+// ModerationBlock it is returned wrapped in an *OpenAIErr; use errors.As to get it.
+type ModerationError struct {
+	Stage      ModerationStage
+	Categories []string
+	// Indexes are the positions of the flagged messages, for the input, or of
+	// the flagged choices, for the output.
+	Indexes []int
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%s flagged by moderation: %s", e.Stage, strings.Join(e.Categories, ", "))
+}
+
+func (g *ModerationGuard) redaction() string {
+	if g.Redaction == "" {
+		return DefaultRedaction
+	}
+	return g.Redaction
+}
+
+// check applies the thresholds to the results of the moderated items, whose
+// positions are given by indexes, and enforces the policy. It returns the
+// positions to redact.
+func (g *ModerationGuard) check(stage ModerationStage, results []ModerationResult, indexes []int) ([]int, *OpenAIErr) {
+	flagged := &ModerationResponse{}
+	moderationErr := &ModerationError{Stage: stage}
+	for i, result := range results {
+		if i < len(indexes) && len(result.CategoriesOver(g.Thresholds)) > 0 {
+			flagged.Results = append(flagged.Results, result)
+			moderationErr.Indexes = append(moderationErr.Indexes, indexes[i])
+		}
+	}
+	if len(moderationErr.Indexes) == 0 {
+		return nil, nil
+	}
+	moderationErr.Categories = flagged.CategoriesOver(g.Thresholds)
+	if g.OnFlagged != nil {
+		g.OnFlagged(moderationErr)
+	}
+	switch g.Policy {
+	case ModerationRedact:
+		return moderationErr.Indexes, nil
+	case ModerationWarn:
+		return nil, nil
+	default:
+		return nil, errContentFlagged(moderationErr)
+	}
+}
+
+// moderateInput moderates the user messages and returns the messages to send.
+// Flagged messages are redacted in a copy, never in place.
+func moderateInput[Messages DefaultMessages | MediaMessages](api OpenAIClient, httpClient HTTPClient, guard *ModerationGuard, messages Messages) (Messages, *OpenAIErr) {
+	switch messages := any(messages).(type) {
+	case DefaultMessages:
+		var texts []string
+		var indexes []int
+		for i, message := range messages {
+			if message.Role == "user" {
+				texts = append(texts, message.Content)
+				indexes = append(indexes, i)
+			}
+		}
+		if len(texts) == 0 {
+			return any(messages).(Messages), nil
+		}
+		res, err := Moderator(api, httpClient, &ModerationRequest[[]string]{Model: guard.Model, Input: texts})
+		if err != nil {
+			return nil, err
+		}
+		redact, err := guard.check(ModerationStageInput, res.Results, indexes)
+		if err != nil {
+			return nil, err
+		}
+		if len(redact) > 0 {
+			messages = append(DefaultMessages(nil), messages...)
+			for _, i := range redact {
+				messages[i].Content = guard.redaction()
+			}
+		}
+		return any(messages).(Messages), nil
+	case MediaMessages:
+		var results []ModerationResult
+		var indexes []int
+		for i, message := range messages {
+			if message.Role != "user" {
+				continue
+			}
+			// Parts of one input are scored together, so each message is moderated on its own.
+			res, err := Moderator(api, httpClient, &ModerationRequest[[]MediaMessage]{Model: guard.Model, Input: message.Content})
+			if err != nil {
+				return nil, err
+			}
+			if len(res.Results) > 0 {
+				results = append(results, res.Results[0])
+				indexes = append(indexes, i)
+			}
+		}
+		redact, err := guard.check(ModerationStageInput, results, indexes)
+		if err != nil {
+			return nil, err
+		}
+		if len(redact) > 0 {
+			messages = append(MediaMessages(nil), messages...)
+			for _, i := range redact {
+				messages[i].Content = []MediaMessage{TextContent(guard.redaction())}
+			}
+		}
+		return any(messages).(Messages), nil
+	}
+	return messages, nil
+}
+
+// moderateOutput moderates the choices of res, redacting them in place.
+func moderateOutput(api OpenAIClient, httpClient HTTPClient, guard *ModerationGuard, res *CompletionResponse) *OpenAIErr {
+	var texts []string
+	var indexes []int
+	for i, choice := range res.Choices {
+		if choice.Message.Content != "" {
+			texts = append(texts, choice.Message.Content)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	moderation, err := Moderator(api, httpClient, &ModerationRequest[[]string]{Model: guard.Model, Input: texts})
+	if err != nil {
+		return err
+	}
+	redact, err := guard.check(ModerationStageOutput, moderation.Results, indexes)
+	if err != nil {
+		return err
+	}
+	for _, i := range redact {
+		res.Choices[i].Message.Content = guard.redaction()
+	}
+	return nil
+}
+
+// ModeratedChatCompletion wraps ChatCompletion with moderation of the user
+// messages and, optionally, of the model's answer, as configured by guard.
+// Flagged content is blocked, redacted or only reported according to
+// guard.Policy. Blocked calls return an *OpenAIErr of type "content_flagged"
+// wrapping a *ModerationError that lists the categories that triggered.
+// body is never modified.
+func ModeratedChatCompletion[Messages DefaultMessages | MediaMessages](api OpenAIClient, httpClient HTTPClient, guard *ModerationGuard, body *CompletionRequest[Messages]) (*CompletionResponse, *OpenAIErr) {
+	if guard == nil {
+		guard = &ModerationGuard{}
+	}
+	if guard.ModerateInput || !guard.ModerateOutput {
+		messages, err := moderateInput(api, httpClient, guard, body.Messages)
+		if err != nil {
+			return nil, err
+		}
+		moderated := *body
+		moderated.Messages = messages
+		body = &moderated
+	}
+	res, err := ChatCompletion(api, httpClient, body)
+	if err != nil {
+		return nil, err
+	}
+	if guard.ModerateOutput {
+		if err := moderateOutput(api, httpClient, guard, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
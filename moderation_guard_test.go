@@ -0,0 +1,161 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockGuardHTTPClient flags every moderated text containing "bad" and answers
+// chats with answer. It records the messages sent to the chat endpoint.
+type MockGuardHTTPClient struct {
+	answer      string
+	moderations int
+	sent        []Message[string]
+}
+
+func (c *MockGuardHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body any
+	if strings.HasSuffix(url, "/moderations") {
+		c.moderations++
+		var req ModerationRequest[[]string]
+		if err := json.Unmarshal(b, &req); err != nil {
+			return nil, err
+		}
+		res := ModerationResponse{}
+		for _, input := range req.Input {
+			bad := strings.Contains(input, "bad")
+			result := ModerationResult{Flagged: bad}
+			result.Categories.Harassment = bad
+			result.CategoryScores.Harassment = 0.1
+			if bad {
+				result.CategoryScores.Harassment = 0.9
+			}
+			res.Results = append(res.Results, result)
+		}
+		body = res
+	} else {
+		var req CompletionRequest[DefaultMessages]
+		if err := json.Unmarshal(b, &req); err != nil {
+			return nil, err
+		}
+		c.sent = req.Messages
+		body = CompletionResponse{Choices: []Choice{{Message: Message[string]{Role: "assistant", Content: c.answer}}}}
+	}
+	resBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(ioReader(resBytes))}, nil
+}
+
+func (c *MockGuardHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func TestModeratedChatCompletion(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	newBody := func(text string) *CompletionRequest[DefaultMessages] {
+		return &CompletionRequest[DefaultMessages]{
+			Model: "gpt-4o-mini",
+			Messages: DefaultMessages{
+				{Role: "system", Content: "You are a bad assistant."},
+				{Role: "user", Content: text},
+			},
+		}
+	}
+
+	t.Run("AllowsCleanInput", func(t *testing.T) {
+		httpClient := &MockGuardHTTPClient{answer: "Hello"}
+		res, err := ModeratedChatCompletion(api, httpClient, nil, newBody("hi"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Choices[0].Message.Content != "Hello" || httpClient.moderations != 1 {
+			t.Errorf("unexpected response %+v after %d moderations", res, httpClient.moderations)
+		}
+	})
+
+	t.Run("BlocksInput", func(t *testing.T) {
+		httpClient := &MockGuardHTTPClient{answer: "Hello"}
+		_, err := ModeratedChatCompletion(api, httpClient, &ModerationGuard{}, newBody("you are bad"))
+		var moderationErr *ModerationError
+		if err == nil || !errors.As(err, &moderationErr) {
+			t.Fatalf("expected a moderation error, got %v", err)
+		}
+		if err.Err.Type != "content_flagged" || moderationErr.Stage != ModerationStageInput {
+			t.Errorf("unexpected error: %+v", err)
+		}
+		if !reflect.DeepEqual(moderationErr.Categories, []string{ModerationHarassment}) || !reflect.DeepEqual(moderationErr.Indexes, []int{1}) {
+			t.Errorf("unexpected flagged content: %+v", moderationErr)
+		}
+		if httpClient.sent != nil {
+			t.Error("blocked input should not be sent to the chat")
+		}
+	})
+
+	t.Run("RedactsInput", func(t *testing.T) {
+		httpClient := &MockGuardHTTPClient{answer: "Hello"}
+		body := newBody("you are bad")
+		_, err := ModeratedChatCompletion(api, httpClient, &ModerationGuard{Policy: ModerationRedact, Redaction: "***"}, body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if httpClient.sent[1].Content != "***" || httpClient.sent[0].Content != body.Messages[0].Content {
+			t.Errorf("unexpected messages sent: %+v", httpClient.sent)
+		}
+		if body.Messages[1].Content != "you are bad" {
+			t.Error("the request body should not be modified")
+		}
+	})
+
+	t.Run("WarnsAndThresholds", func(t *testing.T) {
+		var warnings []*ModerationError
+		guard := &ModerationGuard{
+			Policy:         ModerationWarn,
+			Thresholds:     ModerationThresholds{ModerationHarassment: 0.05},
+			ModerateInput:  true,
+			ModerateOutput: true,
+			OnFlagged:      func(err *ModerationError) { warnings = append(warnings, err) },
+		}
+		httpClient := &MockGuardHTTPClient{answer: "Hello"}
+		res, err := ModeratedChatCompletion(api, httpClient, guard, newBody("hi"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Choices[0].Message.Content != "Hello" {
+			t.Errorf("warned output should be kept, got %q", res.Choices[0].Message.Content)
+		}
+		if len(warnings) != 2 || warnings[0].Stage != ModerationStageInput || warnings[1].Stage != ModerationStageOutput {
+			t.Errorf("expected input and output warnings, got %+v", warnings)
+		}
+	})
+
+	t.Run("ModeratesOutput", func(t *testing.T) {
+		guard := &ModerationGuard{Policy: ModerationRedact, ModerateOutput: true}
+		httpClient := &MockGuardHTTPClient{answer: "bad answer"}
+		res, err := ModeratedChatCompletion(api, httpClient, guard, newBody("you are bad"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Choices[0].Message.Content != DefaultRedaction || httpClient.moderations != 1 {
+			t.Errorf("expected only the output to be moderated and redacted, got %q after %d moderations", res.Choices[0].Message.Content, httpClient.moderations)
+		}
+		guard.Policy = ModerationBlock
+		_, err = ModeratedChatCompletion(api, httpClient, guard, newBody("hi"))
+		var moderationErr *ModerationError
+		if !errors.As(err, &moderationErr) || moderationErr.Stage != ModerationStageOutput {
+			t.Errorf("expected the output to be blocked, got %v", err)
+		}
+	})
+}
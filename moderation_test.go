@@ -0,0 +1,153 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+const moderationResponseBody = `{
+	"id": "modr-1",
+	"model": "omni-moderation-latest",
+	"results": [
+		{
+			"flagged": true,
+			"categories": {"harassment": true, "violence": true, "hate": false},
+			"category_scores": {"harassment": 0.91, "violence": 0.72, "hate": 0.35},
+			"category_applied_input_types": {"harassment": ["text"], "violence": ["text", "image"]}
+		},
+		{
+			"flagged": false,
+			"categories": {"harassment": false},
+			"category_scores": {"harassment": 0.2, "self-harm/intent": 0.4}
+		}
+	]
+}`
+
+func TestModerator(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	t.Run("DecodesResults", func(t *testing.T) {
+		httpClient := &MockResponseHTTPClient{statusCode: http.StatusOK, body: moderationResponseBody}
+		res, err := Moderator(api, httpClient, &ModerationRequest[[]string]{Input: []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Results) != 2 || !res.Flagged() {
+			t.Fatalf("unexpected results: %+v", res.Results)
+		}
+		result := res.Results[0]
+		if result.CategoryScores.Harassment != 0.91 || result.CategoryScores.Violence != 0.72 {
+			t.Errorf("unexpected scores: %+v", result.CategoryScores)
+		}
+		if !reflect.DeepEqual(result.CategoryAppliedInputTypes.Violence, []string{"text", "image"}) {
+			t.Errorf("unexpected applied input types: %+v", result.CategoryAppliedInputTypes)
+		}
+		if got, want := result.FlaggedCategories(), []string{ModerationHarassment, ModerationViolence}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected flagged categories %v, got %v", want, got)
+		}
+	})
+	t.Run("HTTPError", func(t *testing.T) {
+		httpClient := &MockResponseHTTPClient{statusCode: http.StatusBadRequest, body: `{"error":{"message":"bad input","type":"invalid_request_error"}}`}
+		_, err := Moderator(api, httpClient, &ModerationRequest[string]{Input: "a"})
+		if err == nil || err.Status() != http.StatusBadRequest || err.Error() != "bad input" {
+			t.Fatalf("expected a 400 error, got %v", err)
+		}
+	})
+}
+
+func TestModerationThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds ModerationThresholds
+		want       []string
+	}{
+		{"APIFlags", nil, []string{ModerationHarassment, ModerationViolence}},
+		{"Stricter", ModerationThresholds{ModerationHate: 0.3, ModerationSelfHarmIntent: 0.3}, []string{ModerationHarassment, ModerationHate, ModerationSelfHarmIntent, ModerationViolence}},
+		{"Looser", ModerationThresholds{ModerationHarassment: 0.95, ModerationViolence: 0.8}, nil},
+	}
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	res, err := Moderator(api, &MockResponseHTTPClient{statusCode: http.StatusOK, body: moderationResponseBody}, &ModerationRequest[string]{Input: "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := res.CategoriesOver(test.thresholds); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+// MockModerationHTTPClient records the request body and answers with moderationResponseBody.
+type MockModerationHTTPClient struct {
+	body []byte
+}
+
+func (c *MockModerationHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	c.body, _ = io.ReadAll(opts.Body)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(moderationResponseBody))}, nil
+}
+
+func (c *MockModerationHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func TestMultimodalModeration(t *testing.T) {
+	api := &MockClient{baseUrl: "https://fake.api.openai.com/v1"}
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	t.Run("TextAndImage", func(t *testing.T) {
+		httpClient := &MockModerationHTTPClient{}
+		_, err := Moderator(api, httpClient, &ModerationRequest[[]MediaMessage]{
+			Model: "omni-moderation-latest",
+			Input: []MediaMessage{TextContent("my avatar"), ImageContent(ImageDataUrl(png))},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var sent struct {
+			Input []struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				ImageUrl struct {
+					Url string `json:"url"`
+				} `json:"image_url"`
+			} `json:"input"`
+		}
+		if err := json.Unmarshal(httpClient.body, &sent); err != nil {
+			t.Fatalf("invalid request body %s: %v", httpClient.body, err)
+		}
+		if len(sent.Input) != 2 || sent.Input[0].Type != "text" || sent.Input[0].Text != "my avatar" || sent.Input[1].Type != "image_url" {
+			t.Fatalf("unexpected input: %s", httpClient.body)
+		}
+		if url := sent.Input[1].ImageUrl.Url; !strings.HasPrefix(url, "data:image/png;base64,") {
+			t.Errorf("expected a PNG data URL, got %s", url)
+		}
+	})
+	invalid := []struct {
+		name  string
+		model string
+		input []MediaMessage
+	}{
+		{"ImageWithTextModel", "text-moderation-latest", []MediaMessage{ImageContent(ImageUrl("https://example.com/a.png"))}},
+		{"MissingImageUrl", "", []MediaMessage{{Type: "image_url"}}},
+		{"UnknownType", "", []MediaMessage{{Type: "audio"}}},
+	}
+	for _, test := range invalid {
+		t.Run(test.name, func(t *testing.T) {
+			httpClient := &MockModerationHTTPClient{}
+			_, err := Moderator(api, httpClient, &ModerationRequest[[]MediaMessage]{Model: test.model, Input: test.input})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if httpClient.body != nil {
+				t.Error("invalid request should not be sent")
+			}
+		})
+	}
+}
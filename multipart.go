@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/Simplou/goxios"
+)
+
+type (
+	// formField is a plain text field of a multipart form.
+	formField struct {
+		Key, Value string
+	}
+
+	// formFile is a file part of a multipart form.
+	formFile struct {
+		Field, Filename, ContentType string
+		Content                      io.Reader
+	}
+
+	// multipartBody streams a multipart/form-data body through an io.Pipe, so files
+	// are never held in memory. It must be closed once the request has been sent.
+	multipartBody struct {
+		*io.PipeReader
+		contentType string
+		done        chan struct{}
+		err         *OpenAIErr
+	}
+)
+
+// multipartForm starts encoding fields and files as a multipart/form-data body.
+// Empty fields are skipped.
+func multipartForm(fields []formField, files []formFile) *multipartBody {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	body := &multipartBody{PipeReader: pr, contentType: writer.FormDataContentType(), done: make(chan struct{})}
+	go func() {
+		defer close(body.done)
+		if err := writeMultipartForm(writer, fields, files); err != nil {
+			pw.CloseWithError(err)
+			if !errors.Is(err, io.ErrClosedPipe) {
+				body.err = asOpenAIErr(err, errCannotCopyFileContent)
+			}
+			return
+		}
+		pw.Close()
+	}()
+	return body
+}
+
+func writeMultipartForm(writer *multipart.Writer, fields []formField, files []formFile) error {
+	for _, field := range fields {
+		if field.Value == "" {
+			continue
+		}
+		if err := writer.WriteField(field.Key, field.Value); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		part, err := createFormFile(writer, file)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Close stops the upload and returns the error that interrupted encoding the form,
+// if any. Errors caused by the reader being closed early are not reported.
+func (b *multipartBody) Close() *OpenAIErr {
+	b.PipeReader.Close()
+	<-b.done
+	return b.err
+}
+
+// postMultipart sends form to path. Errors from encoding the form, such as a failed
+// read of an uploaded file, take precedence over the transport error they cause.
+func postMultipart(api OpenAIClient, httpClient HTTPClient, path string, form *multipartBody) (*http.Response, *OpenAIErr) {
+	res, err := httpClient.Post(api.BaseURL()+path, &goxios.RequestOpts{
+		Headers: requestHeaders(api, goxios.Header{Key: "Content-Type", Value: form.contentType}),
+		Body:    form,
+	})
+	if formErr := form.Close(); formErr != nil {
+		if res != nil && res.Body != nil {
+			res.Body.Close()
+		}
+		return nil, formErr
+	}
+	return checkResponse(res, err)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func createFormFile(writer *multipart.Writer, file formFile) (io.Writer, error) {
+	if file.ContentType == "" {
+		return writer.CreateFormFile(file.Field, file.Filename)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(file.Field), quoteEscaper.Replace(file.Filename)))
+	h.Set("Content-Type", file.ContentType)
+	return writer.CreatePart(h)
+}
@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Defaults of the helpers that poll a resource until it is done.
+const (
+	defaultPollInterval    = 5 * time.Second
+	defaultPollMaxInterval = time.Minute
+	defaultPollMaxRetries  = 5
+)
+
+// poll calls fetch until done reports true for the resource it returns. The
+// delay between two calls starts at interval and doubles up to maxInterval.
+// Calls failing with a rate limit or server error are retried up to maxRetries
+// consecutive times; other errors are returned. Zero values use the defaults.
+func poll[T any](ctx context.Context, interval, maxInterval time.Duration, maxRetries int, fetch func() (*T, *OpenAIErr), done func(*T) bool) (*T, *OpenAIErr) {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultPollMaxInterval
+	}
+	if maxRetries <= 0 {
+		maxRetries = defaultPollMaxRetries
+	}
+	retries := 0
+	for {
+		resource, err := fetch()
+		switch {
+		case err == nil:
+			retries = 0
+			if done(resource) {
+				return resource, nil
+			}
+		case retryable(err) && retries < maxRetries:
+			retries++
+		default:
+			return nil, err
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, errCanceled(err)
+		}
+		interval = min(2*interval, maxInterval)
+	}
+}
+
+// retryable reports whether a request that failed with err may succeed later:
+// the API answered with a rate limit or a server error, or the request could
+// not be sent or its response read. The other errors of the client, such as an
+// invalid JSON response, fail the same way every time.
+func retryable(err *OpenAIErr) bool {
+	if err.internal {
+		return err.Err.Type == "cannot_send_request" || err.Err.Type == "cannot_read_body"
+	}
+	return err.Status() == http.StatusTooManyRequests || err.Status() >= http.StatusInternalServerError
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
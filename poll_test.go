@@ -0,0 +1,39 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPollRetries(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   *OpenAIErr
+		calls int
+	}{
+		{"RateLimit", &OpenAIErr{Err: JSONErr{Message: "slow down"}, status: http.StatusTooManyRequests}, 3},
+		{"ServerError", &OpenAIErr{Err: JSONErr{Message: "overloaded"}, status: http.StatusServiceUnavailable}, 3},
+		{"Transport", errCannotSendRequest(errors.New("connection refused")), 3},
+		{"InvalidJSON", errCannotDecodeJSON(errors.New("unexpected EOF")), 1},
+		{"InvalidRequest", errInvalidRequest(errors.New("bad id")), 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			fetch := func() (*int, *OpenAIErr) {
+				calls++
+				if calls < 3 {
+					return nil, test.err
+				}
+				return &calls, nil
+			}
+			poll(context.Background(), time.Millisecond, time.Millisecond, 5, fetch, func(*int) bool { return true })
+			if calls != test.calls {
+				t.Errorf("expected %d calls, got %d", test.calls, calls)
+			}
+		})
+	}
+}
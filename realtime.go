@@ -0,0 +1,561 @@
+package openai
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// Types of the events sent to the Realtime API.
+const (
+	RealtimeClientSessionUpdate          = "session.update"
+	RealtimeClientInputAudioBufferAppend = "input_audio_buffer.append"
+	RealtimeClientInputAudioBufferCommit = "input_audio_buffer.commit"
+	RealtimeClientInputAudioBufferClear  = "input_audio_buffer.clear"
+	RealtimeClientConversationItemCreate = "conversation.item.create"
+	RealtimeClientResponseCreate         = "response.create"
+	RealtimeClientResponseCancel         = "response.cancel"
+)
+
+// Types of the events received from the Realtime API.
+const (
+	RealtimeEventError                            = "error"
+	RealtimeEventSessionCreated                   = "session.created"
+	RealtimeEventSessionUpdated                   = "session.updated"
+	RealtimeEventInputAudioBufferCommitted        = "input_audio_buffer.committed"
+	RealtimeEventInputAudioBufferCleared          = "input_audio_buffer.cleared"
+	RealtimeEventInputAudioBufferSpeechStarted    = "input_audio_buffer.speech_started"
+	RealtimeEventInputAudioBufferSpeechStopped    = "input_audio_buffer.speech_stopped"
+	RealtimeEventConversationItemCreated          = "conversation.item.created"
+	RealtimeEventInputAudioTranscriptionCompleted = "conversation.item.input_audio_transcription.completed"
+	RealtimeEventInputAudioTranscriptionFailed    = "conversation.item.input_audio_transcription.failed"
+	RealtimeEventResponseCreated                  = "response.created"
+	RealtimeEventResponseDone                     = "response.done"
+	RealtimeEventOutputItemAdded                  = "response.output_item.added"
+	RealtimeEventOutputItemDone                   = "response.output_item.done"
+	RealtimeEventTextDelta                        = "response.text.delta"
+	RealtimeEventTextDone                         = "response.text.done"
+	RealtimeEventAudioDelta                       = "response.audio.delta"
+	RealtimeEventAudioDone                        = "response.audio.done"
+	RealtimeEventAudioTranscriptDelta             = "response.audio_transcript.delta"
+	RealtimeEventAudioTranscriptDone              = "response.audio_transcript.done"
+	RealtimeEventFunctionCallArgumentsDelta       = "response.function_call_arguments.delta"
+	RealtimeEventFunctionCallArgumentsDone        = "response.function_call_arguments.done"
+	RealtimeEventRateLimitsUpdated                = "rate_limits.updated"
+	// RealtimeEventReconnected is not sent by the API: Run emits it after the
+	// connection was lost and a new session opened. The conversation of the
+	// lost session is not restored.
+	RealtimeEventReconnected = "client.reconnected"
+)
+
+// Defaults of RealtimeOpts.
+const (
+	defaultRealtimeMaxReconnects        = 5
+	defaultRealtimeReconnectInterval    = 500 * time.Millisecond
+	defaultRealtimeReconnectMaxInterval = 10 * time.Second
+	// DefaultRealtimeAudioFrame is the duration of the audio sent by each
+	// input_audio_buffer.append event.
+	DefaultRealtimeAudioFrame = 100 * time.Millisecond
+)
+
+// realtimeBeta selects the version of the Realtime API.
+var realtimeBeta = goxios.Header{Key: "OpenAI-Beta", Value: "realtime=v1"}
+
+type (
+	// RealtimeSession configures a realtime session. Audio formats are pcm16,
+	// g711_ulaw or g711_alaw; pcm16 audio is 24kHz mono little-endian.
+	RealtimeSession struct {
+		Id                      string                 `json:"id,omitempty"`
+		Model                   string                 `json:"model,omitempty"`
+		Modalities              []string               `json:"modalities,omitempty"`
+		Instructions            string                 `json:"instructions,omitempty"`
+		Voice                   string                 `json:"voice,omitempty"`
+		InputAudioFormat        string                 `json:"input_audio_format,omitempty"`
+		OutputAudioFormat       string                 `json:"output_audio_format,omitempty"`
+		InputAudioTranscription *RealtimeTranscription `json:"input_audio_transcription,omitempty"`
+		TurnDetection           *RealtimeTurnDetection `json:"turn_detection,omitempty"`
+		Tools                   []RealtimeTool         `json:"tools,omitempty"`
+		ToolChoice              any                    `json:"tool_choice,omitempty"`
+		Temperature             *float64               `json:"temperature,omitempty"`
+		// MaxResponseOutputTokens is a number of tokens or "inf".
+		MaxResponseOutputTokens any `json:"max_response_output_tokens,omitempty"`
+	}
+
+	// RealtimeTranscription enables the transcription of the input audio.
+	RealtimeTranscription struct {
+		Model    string `json:"model"`
+		Language string `json:"language,omitempty"`
+		Prompt   string `json:"prompt,omitempty"`
+	}
+
+	// RealtimeTurnDetection detects the end of the user turns, committing the
+	// input audio and, unless CreateResponse is false, creating a response.
+	RealtimeTurnDetection struct {
+		// Type is server_vad or semantic_vad.
+		Type              string   `json:"type"`
+		Threshold         *float64 `json:"threshold,omitempty"`
+		PrefixPaddingMs   int      `json:"prefix_padding_ms,omitempty"`
+		SilenceDurationMs int      `json:"silence_duration_ms,omitempty"`
+		CreateResponse    *bool    `json:"create_response,omitempty"`
+		InterruptResponse *bool    `json:"interrupt_response,omitempty"`
+	}
+
+	// RealtimeTool is a function the model can call, defined as in chat completions.
+	RealtimeTool struct {
+		Function
+	}
+
+	// RealtimeItem is an item of the conversation: a message, a function call
+	// or the output of a function call.
+	RealtimeItem struct {
+		Id     string `json:"id,omitempty"`
+		Type   string `json:"type"`
+		Status string `json:"status,omitempty"`
+		// Role and Content describe messages.
+		Role    string            `json:"role,omitempty"`
+		Content []RealtimeContent `json:"content,omitempty"`
+		// CallId, Name and Arguments describe function calls, and CallId and
+		// Output their output.
+		CallId    string `json:"call_id,omitempty"`
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+		Output    string `json:"output,omitempty"`
+	}
+
+	// RealtimeContent is a part of a message: input_text, input_audio, text or audio.
+	RealtimeContent struct {
+		Type       string `json:"type"`
+		Text       string `json:"text,omitempty"`
+		Audio      string `json:"audio,omitempty"`
+		Transcript string `json:"transcript,omitempty"`
+	}
+
+	// RealtimeResponseConfig overrides the session configuration for one response.
+	RealtimeResponseConfig struct {
+		Modalities        []string       `json:"modalities,omitempty"`
+		Instructions      string         `json:"instructions,omitempty"`
+		Voice             string         `json:"voice,omitempty"`
+		OutputAudioFormat string         `json:"output_audio_format,omitempty"`
+		Tools             []RealtimeTool `json:"tools,omitempty"`
+		ToolChoice        any            `json:"tool_choice,omitempty"`
+		Temperature       *float64       `json:"temperature,omitempty"`
+		MaxOutputTokens   any            `json:"max_response_output_tokens,omitempty"`
+		// Conversation is auto, or none for a response out of the conversation.
+		Conversation string            `json:"conversation,omitempty"`
+		Metadata     map[string]string `json:"metadata,omitempty"`
+		Input        []RealtimeItem    `json:"input,omitempty"`
+	}
+
+	// RealtimeResponse is a response of the model, sent with response.created
+	// and response.done.
+	RealtimeResponse struct {
+		Id            string          `json:"id"`
+		Object        string          `json:"object"`
+		Status        string          `json:"status"`
+		StatusDetails json.RawMessage `json:"status_details,omitempty"`
+		Output        []RealtimeItem  `json:"output"`
+		Metadata      map[string]any  `json:"metadata,omitempty"`
+		Usage         *RealtimeUsage  `json:"usage,omitempty"`
+	}
+
+	RealtimeUsage struct {
+		TotalTokens  int `json:"total_tokens"`
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	}
+
+	// RealtimeClientEvent is an event sent to the API. The fields set depend on Type.
+	RealtimeClientEvent struct {
+		Type     string                  `json:"type"`
+		EventId  string                  `json:"event_id,omitempty"`
+		Session  *RealtimeSession        `json:"session,omitempty"`
+		Audio    string                  `json:"audio,omitempty"`
+		Item     *RealtimeItem           `json:"item,omitempty"`
+		Response *RealtimeResponseConfig `json:"response,omitempty"`
+	}
+
+	// RealtimeServerEvent is an event received from the API. The fields set
+	// depend on Type: Delta holds base64 audio in audio deltas, see Audio, and
+	// text in the other deltas; Transcript the done transcripts and the input
+	// audio transcriptions; CallId, Name and Arguments the function calls.
+	RealtimeServerEvent struct {
+		Type           string            `json:"type"`
+		EventId        string            `json:"event_id"`
+		Session        *RealtimeSession  `json:"session,omitempty"`
+		Item           *RealtimeItem     `json:"item,omitempty"`
+		Response       *RealtimeResponse `json:"response,omitempty"`
+		ResponseId     string            `json:"response_id,omitempty"`
+		ItemId         string            `json:"item_id,omitempty"`
+		PreviousItemId string            `json:"previous_item_id,omitempty"`
+		OutputIndex    int               `json:"output_index"`
+		ContentIndex   int               `json:"content_index"`
+		Delta          string            `json:"delta,omitempty"`
+		Text           string            `json:"text,omitempty"`
+		Transcript     string            `json:"transcript,omitempty"`
+		CallId         string            `json:"call_id,omitempty"`
+		Name           string            `json:"name,omitempty"`
+		Arguments      string            `json:"arguments,omitempty"`
+		AudioStartMs   int               `json:"audio_start_ms,omitempty"`
+		AudioEndMs     int               `json:"audio_end_ms,omitempty"`
+		Error          *JSONErr          `json:"error,omitempty"`
+	}
+)
+
+func (t RealtimeTool) MarshalJSON() ([]byte, error) {
+	type tool RealtimeTool
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		tool
+	}{"function", tool(t)})
+}
+
+// RealtimeFunctionTools converts chat completion tools to realtime tools.
+func RealtimeFunctionTools(tools ...Tool) []RealtimeTool {
+	realtimeTools := make([]RealtimeTool, len(tools))
+	for i, tool := range tools {
+		realtimeTools[i] = RealtimeTool{Function: tool.Function}
+	}
+	return realtimeTools
+}
+
+// Audio decodes the audio of a response.audio.delta event.
+func (e *RealtimeServerEvent) Audio() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(e.Delta)
+}
+
+// PCM16Bytes encodes samples as little-endian PCM16 audio.
+func PCM16Bytes(samples []int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(sample))
+	}
+	return b
+}
+
+// PCM16Samples decodes little-endian PCM16 audio. A trailing odd byte is ignored.
+func PCM16Samples(b []byte) []int16 {
+	samples := make([]int16, len(b)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(b[2*i:]))
+	}
+	return samples
+}
+
+// PCM16Frames splits PCM16 24kHz mono audio in frames of d, the last one
+// possibly shorter. The frames share the memory of pcm.
+func PCM16Frames(pcm []byte, d time.Duration) [][]byte {
+	size := max(PCM16Mono24kHz.Bytes(d), PCM16Mono24kHz.BlockAlign())
+	var frames [][]byte
+	for len(pcm) > 0 {
+		n := min(size, len(pcm))
+		frames = append(frames, pcm[:n])
+		pcm = pcm[n:]
+	}
+	return frames
+}
+
+// RealtimeHandler handles an event received by RealtimeConn.Run. An error
+// stops the event loop.
+type RealtimeHandler func(ctx context.Context, event *RealtimeServerEvent) error
+
+// RealtimeOpts configures ConnectRealtime. Zero values use the defaults.
+type RealtimeOpts struct {
+	// Model defaults to DefaultRealtimeModel.
+	Model string
+	// Session, when set, is sent once connected, and again after every reconnect.
+	Session *RealtimeSession
+	// Handlers are called by Run with the arguments of the functions called by
+	// the model, by name. Their outputs are sent back to the conversation and a
+	// new response is created once the response calling them is done. Handler
+	// errors are sent as an output starting with "error: ".
+	Handlers map[string]ToolHandler
+	// MaxReconnects is the number of consecutive reconnection attempts made by
+	// Run when the connection is lost, 5 by default. Negative values disable them.
+	MaxReconnects int
+	// ReconnectInterval is the delay before the first reconnection attempt,
+	// 500ms by default. It doubles after every failed attempt, up to 10s.
+	ReconnectInterval time.Duration
+	// TLSConfig configures wss connections.
+	TLSConfig *tls.Config
+	// AudioFrame is the duration of the audio of each event sent by
+	// AppendAudio, DefaultRealtimeAudioFrame by default.
+	AudioFrame time.Duration
+}
+
+// RealtimeConn is a connection to the Realtime API. Its send methods are safe
+// for concurrent use, such as streaming microphone audio while Run reads events.
+type RealtimeConn struct {
+	api  OpenAIClient
+	url  string
+	opts RealtimeOpts
+
+	mu      sync.Mutex
+	ws      *wsConn
+	session *RealtimeSession
+	closed  bool
+}
+
+// realtimeURL returns the WebSocket URL of the Realtime API of api.
+func realtimeURL(api OpenAIClient, model string) string {
+	base := api.BaseURL()
+	if rest, ok := strings.CutPrefix(base, "http"); ok {
+		base = "ws" + rest
+	}
+	return base + "/realtime?model=" + url.QueryEscape(model)
+}
+
+// ConnectRealtime opens a realtime session over a WebSocket. The URL is built
+// from the base URL of api, so a client pointing to a local stand-in server
+// connects to it. Call Run to receive the events of the session.
+func ConnectRealtime(ctx context.Context, api OpenAIClient, opts *RealtimeOpts) (*RealtimeConn, *OpenAIErr) {
+	if opts == nil {
+		opts = &RealtimeOpts{}
+	}
+	c := &RealtimeConn{api: api, opts: *opts, session: opts.Session}
+	if c.opts.Model == "" {
+		c.opts.Model = DefaultRealtimeModel
+	}
+	if c.opts.AudioFrame <= 0 {
+		c.opts.AudioFrame = DefaultRealtimeAudioFrame
+	}
+	c.url = realtimeURL(api, c.opts.Model)
+	if err := c.dial(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial opens a new connection, replacing the current one, and sends the last
+// session configuration.
+func (c *RealtimeConn) dial(ctx context.Context) *OpenAIErr {
+	ws, err := dialWebSocket(ctx, c.url, requestHeaders(c.api, realtimeBeta), c.opts.TLSConfig)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	old, session := c.ws, c.session
+	c.ws = ws
+	c.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	if session != nil {
+		return c.Send(&RealtimeClientEvent{Type: RealtimeClientSessionUpdate, Session: session})
+	}
+	return nil
+}
+
+// Send sends an event to the API.
+func (c *RealtimeConn) Send(event *RealtimeClientEvent) *OpenAIErr {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errCannotMarshalJSON(err)
+	}
+	c.mu.Lock()
+	ws, closed := c.ws, c.closed
+	if event.Type == RealtimeClientSessionUpdate {
+		c.session = event.Session
+	}
+	c.mu.Unlock()
+	if closed {
+		return errCannotSendRequest(errors.New("realtime connection closed"))
+	}
+	if err := ws.writeFrame(wsText, b); err != nil {
+		return errCannotSendRequest(err)
+	}
+	return nil
+}
+
+// UpdateSession updates the configuration of the session. It is sent again
+// after reconnects.
+func (c *RealtimeConn) UpdateSession(session *RealtimeSession) *OpenAIErr {
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientSessionUpdate, Session: session})
+}
+
+// AppendAudio appends PCM16 24kHz mono audio to the input audio buffer, sent
+// in frames of RealtimeOpts.AudioFrame.
+func (c *RealtimeConn) AppendAudio(pcm []byte) *OpenAIErr {
+	if len(pcm)%PCM16Mono24kHz.BlockAlign() != 0 {
+		return errInvalidAudio(fmt.Errorf("pcm16 audio of %d bytes is not made of whole samples", len(pcm)))
+	}
+	for _, frame := range PCM16Frames(pcm, c.opts.AudioFrame) {
+		event := &RealtimeClientEvent{Type: RealtimeClientInputAudioBufferAppend, Audio: base64.StdEncoding.EncodeToString(frame)}
+		if err := c.Send(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitAudio commits the input audio buffer as a user message. It is only
+// needed when turn detection is disabled.
+func (c *RealtimeConn) CommitAudio() *OpenAIErr {
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientInputAudioBufferCommit})
+}
+
+// ClearAudio discards the input audio buffer.
+func (c *RealtimeConn) ClearAudio() *OpenAIErr {
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientInputAudioBufferClear})
+}
+
+// SendText adds a text message of role to the conversation.
+func (c *RealtimeConn) SendText(role, text string) *OpenAIErr {
+	contentType := "input_text"
+	if role == "assistant" {
+		contentType = "text"
+	}
+	item := &RealtimeItem{Type: "message", Role: role, Content: []RealtimeContent{{Type: contentType, Text: text}}}
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientConversationItemCreate, Item: item})
+}
+
+// SendFunctionOutput adds the output of a function call to the conversation.
+func (c *RealtimeConn) SendFunctionOutput(callId, output string) *OpenAIErr {
+	item := &RealtimeItem{Type: "function_call_output", CallId: callId, Output: output}
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientConversationItemCreate, Item: item})
+}
+
+// CreateResponse asks the model to respond. config may be nil.
+func (c *RealtimeConn) CreateResponse(config *RealtimeResponseConfig) *OpenAIErr {
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientResponseCreate, Response: config})
+}
+
+// CancelResponse cancels the response in progress.
+func (c *RealtimeConn) CancelResponse() *OpenAIErr {
+	return c.Send(&RealtimeClientEvent{Type: RealtimeClientResponseCancel})
+}
+
+// Run reads the events of the session and calls handle, which may be nil,
+// with each of them until ctx is done, handle fails or Close is called. The
+// function calls of RealtimeOpts.Handlers are answered before handle is
+// called. When the connection is lost, Run reconnects, sends the session
+// configuration again and emits a RealtimeEventReconnected event. Error
+// events are handled as the other events and do not stop the loop.
+func (c *RealtimeConn) Run(ctx context.Context, handle RealtimeHandler) *OpenAIErr {
+	c.mu.Lock()
+	c.ws.conn.SetReadDeadline(time.Time{})
+	c.mu.Unlock()
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.ws.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	functionOutputs := false
+	for {
+		event, err := c.next()
+		switch {
+		case ctx.Err() != nil:
+			return errCanceled(ctx.Err())
+		case c.isClosed():
+			return nil
+		case errors.Is(err, errInvalidRealtimeEvent):
+			return errCannotDecodeJSON(err)
+		case err != nil:
+			if err := c.reconnect(ctx, err); err != nil {
+				return err
+			}
+			event = &RealtimeServerEvent{Type: RealtimeEventReconnected}
+			functionOutputs = false
+		}
+		switch event.Type {
+		case RealtimeEventFunctionCallArgumentsDone:
+			if handler, ok := c.opts.Handlers[event.Name]; ok {
+				output, err := handler(ctx, event.Arguments)
+				if err != nil {
+					output = "error: " + err.Error()
+				}
+				if err := c.SendFunctionOutput(event.CallId, output); err != nil {
+					return err
+				}
+				functionOutputs = true
+			}
+		case RealtimeEventResponseDone:
+			if functionOutputs {
+				if err := c.CreateResponse(nil); err != nil {
+					return err
+				}
+				functionOutputs = false
+			}
+		}
+		if handle != nil {
+			if err := handle(ctx, event); err != nil {
+				return asOpenAIErr(err, func(err error) *OpenAIErr { return internalError(err, "realtime_handler_error") })
+			}
+		}
+	}
+}
+
+// errInvalidRealtimeEvent is returned by next for messages that are not events.
+var errInvalidRealtimeEvent = errors.New("invalid realtime event")
+
+// next reads the next event.
+func (c *RealtimeConn) next() (*RealtimeServerEvent, error) {
+	c.mu.Lock()
+	ws := c.ws
+	c.mu.Unlock()
+	_, message, err := ws.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	event := new(RealtimeServerEvent)
+	if err := json.Unmarshal(message, event); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidRealtimeEvent, err)
+	}
+	return event, nil
+}
+
+// reconnect opens a new connection after cause made the current one fail.
+func (c *RealtimeConn) reconnect(ctx context.Context, cause error) *OpenAIErr {
+	maxReconnects := c.opts.MaxReconnects
+	if maxReconnects == 0 {
+		maxReconnects = defaultRealtimeMaxReconnects
+	}
+	interval := c.opts.ReconnectInterval
+	if interval <= 0 {
+		interval = defaultRealtimeReconnectInterval
+	}
+	err := errCannotReadBody(cause)
+	for attempt := 0; attempt < maxReconnects; attempt++ {
+		if err := sleep(ctx, interval); err != nil {
+			return errCanceled(err)
+		}
+		if err = c.dial(ctx); err == nil {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if ctx.Err() != nil {
+				c.ws.conn.SetReadDeadline(time.Now())
+			}
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+		interval = min(2*interval, defaultRealtimeReconnectMaxInterval)
+	}
+	return err
+}
+
+func (c *RealtimeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Close closes the connection, stopping Run.
+func (c *RealtimeConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	ws := c.ws
+	c.mu.Unlock()
+	return ws.close()
+}
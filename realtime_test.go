@@ -0,0 +1,200 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acceptWebSocket upgrades a request of a stand-in server to a WebSocket.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	return newWSConn(conn, rw.Reader, false), nil
+}
+
+// realtimeStandIn answers committed audio with a transcript, audio and a
+// function call, and drops its first connection once the function output was
+// answered.
+type realtimeStandIn struct {
+	mu          sync.Mutex
+	connections int
+	events      [][]RealtimeClientEvent
+}
+
+func (s *realtimeStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("model") != DefaultRealtimeModel || r.Header.Get("OpenAI-Beta") != "realtime=v1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+		return
+	}
+	ws, err := acceptWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.conn.Close()
+	s.mu.Lock()
+	s.connections++
+	first := s.connections == 1
+	s.events = append(s.events, nil)
+	s.mu.Unlock()
+	send := func(event string) {
+		ws.writeFrame(wsText, []byte(event))
+	}
+	ws.writeFrame(wsPing, []byte("ping"))
+	// A message split in two frames.
+	ws.conn.Write(append([]byte{wsText, 14}, `{"type":"sessi`...))
+	ws.conn.Write(append([]byte{0x80 | wsContinuation, 16}, `on.created"}    `...))
+	for {
+		_, message, err := ws.readMessage()
+		if err != nil {
+			return
+		}
+		var event RealtimeClientEvent
+		json.Unmarshal(message, &event)
+		s.mu.Lock()
+		s.events[len(s.events)-1] = append(s.events[len(s.events)-1], event)
+		s.mu.Unlock()
+		switch event.Type {
+		case RealtimeClientSessionUpdate:
+			send(`{"type":"session.updated","session":{"id":"sess_1","voice":"alloy"}}`)
+		case RealtimeClientInputAudioBufferCommit:
+			audio := base64.StdEncoding.EncodeToString(PCM16Bytes([]int16{1, -2, 3}))
+			send(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress","output":[]}}`)
+			send(`{"type":"response.audio_transcript.delta","response_id":"resp_1","item_id":"item_1","delta":"Let me "}`)
+			send(`{"type":"response.audio_transcript.delta","response_id":"resp_1","item_id":"item_1","delta":"check."}`)
+			send(`{"type":"response.audio.delta","response_id":"resp_1","item_id":"item_1","delta":"` + audio + `"}`)
+			send(`{"type":"response.function_call_arguments.done","response_id":"resp_1","item_id":"item_2","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Lisbon\"}"}`)
+			send(`{"type":"response.done","response":{"id":"resp_1","status":"completed","output":[],"usage":{"total_tokens":30,"input_tokens":20,"output_tokens":10}}}`)
+		case RealtimeClientResponseCreate:
+			send(`{"type":"response.text.delta","response_id":"resp_2","delta":"It is 21°C."}`)
+			if first {
+				return
+			}
+		}
+	}
+}
+
+func TestRealtime(t *testing.T) {
+	standIn := &realtimeStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+	api := MockClient{server.URL + "/v1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := ConnectRealtime(ctx, api, &RealtimeOpts{Model: "gpt-4o"}); err == nil || err.Status() != http.StatusUnauthorized || err.Err.Code != "invalid_api_key" {
+		t.Fatalf("expected the handshake to be refused, got %v", err)
+	}
+
+	weather := Tool{Type: "function", Function: Function{Name: "get_weather", Description: "Current weather of a city"}}
+	conn, err := ConnectRealtime(ctx, api, &RealtimeOpts{
+		Session: &RealtimeSession{Voice: "alloy", InputAudioFormat: "pcm16", Tools: RealtimeFunctionTools(weather)},
+		Handlers: map[string]ToolHandler{"get_weather": func(ctx context.Context, arguments string) (string, error) {
+			return `{"celsius":21}`, nil
+		}},
+		ReconnectInterval: time.Millisecond,
+		AudioFrame:        10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.AppendAudio(make([]byte, 1201)); err == nil {
+		t.Error("expected an error for audio made of partial samples")
+	}
+	if err := conn.AppendAudio(PCM16Bytes(make([]int16, 600))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := conn.CommitAudio(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var types []string
+	var transcript, text string
+	var audio []byte
+	var usage *RealtimeUsage
+	errDone := errors.New("done")
+	err = conn.Run(ctx, func(ctx context.Context, event *RealtimeServerEvent) error {
+		types = append(types, event.Type)
+		switch event.Type {
+		case RealtimeEventAudioTranscriptDelta:
+			transcript += event.Delta
+		case RealtimeEventTextDelta:
+			text += event.Delta
+		case RealtimeEventAudioDelta:
+			b, err := event.Audio()
+			if err != nil {
+				return err
+			}
+			audio = append(audio, b...)
+		case RealtimeEventResponseDone:
+			usage = event.Response.Usage
+		case RealtimeEventSessionUpdated:
+			if slices.Contains(types, RealtimeEventReconnected) {
+				return errDone
+			}
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected the handler to stop the loop, got %v", err)
+	}
+	want := []string{
+		RealtimeEventSessionCreated, RealtimeEventSessionUpdated,
+		RealtimeEventResponseCreated, RealtimeEventAudioTranscriptDelta, RealtimeEventAudioTranscriptDelta, RealtimeEventAudioDelta, RealtimeEventFunctionCallArgumentsDone, RealtimeEventResponseDone,
+		RealtimeEventTextDelta,
+		RealtimeEventReconnected, RealtimeEventSessionCreated, RealtimeEventSessionUpdated,
+	}
+	if strings.Join(types, " ") != strings.Join(want, " ") {
+		t.Errorf("expected events %v, got %v", want, types)
+	}
+	if transcript != "Let me check." || text != "It is 21°C." || usage == nil || usage.TotalTokens != 30 {
+		t.Errorf("unexpected transcript %q, text %q or usage %+v", transcript, text, usage)
+	}
+	if samples := PCM16Samples(audio); len(samples) != 3 || samples[1] != -2 {
+		t.Errorf("unexpected audio samples %v", samples)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	var sent []string
+	for _, event := range standIn.events[0] {
+		sent = append(sent, event.Type)
+	}
+	wantSent := []string{
+		RealtimeClientSessionUpdate,
+		RealtimeClientInputAudioBufferAppend, RealtimeClientInputAudioBufferAppend, RealtimeClientInputAudioBufferAppend,
+		RealtimeClientInputAudioBufferCommit, RealtimeClientConversationItemCreate, RealtimeClientResponseCreate,
+	}
+	if strings.Join(sent, " ") != strings.Join(wantSent, " ") {
+		t.Errorf("expected sent events %v, got %v", wantSent, sent)
+	}
+	if frame, _ := base64.StdEncoding.DecodeString(standIn.events[0][3].Audio); len(frame) != 240 {
+		t.Errorf("expected a last audio frame of 240 bytes, got %d", len(frame))
+	}
+	if item := standIn.events[0][5].Item; item.Type != "function_call_output" || item.CallId != "call_1" || item.Output != `{"celsius":21}` {
+		t.Errorf("unexpected function output %+v", item)
+	}
+	if session := standIn.events[0][0].Session; len(session.Tools) != 1 || session.Tools[0].Name != "get_weather" {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if len(standIn.events) != 2 || len(standIn.events[1]) != 1 || standIn.events[1][0].Session.Voice != "alloy" {
+		t.Errorf("expected the session to be sent again after reconnecting, got %+v", standIn.events)
+	}
+}
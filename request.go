@@ -0,0 +1,89 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Simplou/goxios"
+)
+
+// postJSON sends body as JSON to path and checks the response with checkResponse.
+func postJSON(api OpenAIClient, httpClient HTTPClient, path string, body any) (*http.Response, *OpenAIErr) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, errCannotMarshalJSON(err)
+	}
+	res, err := httpClient.Post(api.BaseURL()+path, &goxios.RequestOpts{
+		Headers: requestHeaders(api, contentTypeJSON),
+		Body:    ioReader(b),
+	})
+	return checkResponse(res, err)
+}
+
+// get sends a GET request to path and checks the response with checkResponse.
+func get(api OpenAIClient, httpClient HTTPClient, path string, params ...goxios.QueryParam) (*http.Response, *OpenAIErr) {
+	res, err := httpClient.Get(api.BaseURL()+path, &goxios.RequestOpts{
+		Headers:     requestHeaders(api),
+		QueryParams: params,
+	})
+	return checkResponse(res, err)
+}
+
+// getJSON sends a GET request to path and decodes the JSON response.
+func getJSON[T any](api OpenAIClient, httpClient HTTPClient, path string, params ...goxios.QueryParam) (*T, *OpenAIErr) {
+	res, err := get(api, httpClient, path, params...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](res)
+}
+
+// deleteJSON sends a DELETE request to path and decodes the JSON response.
+func deleteJSON[T any](api OpenAIClient, httpClient HTTPDeleteClient, path string) (*T, *OpenAIErr) {
+	res, err := checkResponse(httpClient.Delete(api.BaseURL()+path, &goxios.RequestOpts{
+		Headers: requestHeaders(api),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](res)
+}
+
+// checkResponse checks the transport error first, then the HTTP status, so that
+// every endpoint returns an *OpenAIErr instead of reading a missing response.
+// On success the caller owns the response body.
+func checkResponse(res *http.Response, err error) (*http.Response, *OpenAIErr) {
+	if err != nil {
+		if res != nil && res.Body != nil {
+			res.Body.Close()
+		}
+		return nil, errCannotSendRequest(err)
+	}
+	if res == nil {
+		return nil, errCannotSendRequest(errors.New("no response received"))
+	}
+	if res.Body == nil {
+		res.Body = http.NoBody
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, openaiHttpError(res)
+	}
+	return res, nil
+}
+
+// decodeResponse decodes the JSON body of a checked response and closes it.
+func decodeResponse[T any](res *http.Response) (*T, *OpenAIErr) {
+	response := new(T)
+	if err := goxios.DecodeJSON(res.Body, response); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, closeBody(res.Body, errEmptyBody(errors.New("empty response body")))
+		}
+		return nil, closeBody(res.Body, errCannotDecodeJSON(err))
+	}
+	if err := res.Body.Close(); err != nil {
+		return nil, errCloseBody(err)
+	}
+	return response, nil
+}
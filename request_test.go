@@ -0,0 +1,249 @@
+package openai
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockResponseHTTPClient answers every request with a fixed response or transport error.
+type MockResponseHTTPClient struct {
+	statusCode int
+	body       string
+	nilBody    bool
+	err        error
+}
+
+func (c *MockResponseHTTPClient) response(opts *goxios.RequestOpts) (*http.Response, error) {
+	if opts != nil && opts.Body != nil {
+		io.Copy(io.Discard, opts.Body)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	res := &http.Response{StatusCode: c.statusCode, Header: http.Header{}}
+	if !c.nilBody {
+		res.Body = io.NopCloser(strings.NewReader(c.body))
+	}
+	return res, nil
+}
+
+func (c *MockResponseHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return c.response(opts)
+}
+
+func (c *MockResponseHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return c.response(opts)
+}
+
+func (c *MockResponseHTTPClient) Delete(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return c.response(opts)
+}
+
+// endpointCall calls one endpoint and discards its result.
+type endpointCall func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr
+
+func testEndpoints() map[string]endpointCall {
+	return map[string]endpointCall{
+		"ChatCompletion": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ChatCompletion(api, httpClient, &CompletionRequest[DefaultMessages]{Model: "gpt-4o-mini", Messages: DefaultMessages{{Role: "user", Content: "Hi"}}})
+			return err
+		},
+		"CreateEmbedding": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := CreateEmbedding[string, []float64](api, httpClient, &EmbeddingRequest[string]{Model: "text-embedding-3-small", Input: "Hi"})
+			return err
+		},
+		"UploadFile": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := UploadFile(api, httpClient, &UploadFileRequest{Purpose: FilePurposeBatch, Filename: "requests.jsonl", File: strings.NewReader("{}")})
+			return err
+		},
+		"ListFiles": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ListFiles(api, httpClient, nil)
+			return err
+		},
+		"RetrieveFile": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := RetrieveFile(api, httpClient, "file-1")
+			return err
+		},
+		"FileContent": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			content, err := FileContent(api, httpClient, "file-1")
+			if content != nil {
+				content.Close()
+			}
+			return err
+		},
+		"DeleteFile": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := DeleteFile(api, httpClient.(HTTPDeleteClient), "file-1")
+			return err
+		},
+		"ListModels": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ListModels(api, httpClient)
+			return err
+		},
+		"RetrieveModel": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := RetrieveModel(api, httpClient, "gpt-4o-mini")
+			return err
+		},
+		"DeleteModel": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := DeleteModel(api, httpClient.(HTTPDeleteClient), "ft:gpt-4o-mini:org::1")
+			return err
+		},
+		"CreateResponse": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := CreateResponse(api, httpClient, &CreateResponseRequest{Input: []ResponseItem{InputMessage("user", "Hi")}})
+			return err
+		},
+		"StreamResponse": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			stream, err := StreamResponse(api, httpClient, &CreateResponseRequest{Input: []ResponseItem{InputMessage("user", "Hi")}})
+			if stream != nil {
+				stream.Close()
+			}
+			return err
+		},
+		"RetrieveResponse": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := RetrieveResponse(api, httpClient, "resp_1")
+			return err
+		},
+		"DeleteResponse": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := DeleteResponse(api, httpClient.(HTTPDeleteClient), "resp_1")
+			return err
+		},
+		"ListResponseInputItems": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ListResponseInputItems(api, httpClient, "resp_1", nil)
+			return err
+		},
+		"TextCompletion": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := TextCompletion(api, httpClient, &TextCompletionRequest{Prompt: "Say this is a test"})
+			return err
+		},
+		"StreamTextCompletion": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			stream, err := StreamTextCompletion(api, httpClient, &TextCompletionRequest{Prompt: "Say this is a test"})
+			if stream != nil {
+				stream.Close()
+			}
+			return err
+		},
+		"ImagesGenerations": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ImagesGenerations(api, httpClient, &ImagesGenerationsRequestBody{Prompt: "a gopher"})
+			return err
+		},
+		"ImagesEdits": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ImagesEdits(api, httpClient, &ImagesEditsRequestBody{Image: ImageFromImage("image.png", testImage(2, 2, true)), Prompt: "a gopher"})
+			return err
+		},
+		"ImagesVariations": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := ImagesVariations(api, httpClient, &ImagesVariationsRequestBody{Image: ImageFromImage("image.png", testImage(2, 2, false))})
+			return err
+		},
+		"Moderator": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := Moderator(api, httpClient, &ModerationRequest[string]{Input: "Hi"})
+			return err
+		},
+		"TextToSpeech": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			audio, err := TextToSpeech(api, httpClient, &SpeechRequestBody{Model: TTS1Model, Input: "Hi", Voice: SpeechVoices.Alloy})
+			if audio != nil {
+				audio.Close()
+			}
+			return err
+		},
+		"Transcription": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := Transcription(api, httpClient, &TranscriptionsRequestBody{Model: DefaultTranscriptionModel, Filename: "hi.mp3", Audio: strings.NewReader("audio")})
+			return err
+		},
+		"Translation": func(api OpenAIClient, httpClient HTTPClient) *OpenAIErr {
+			_, err := Translation(api, httpClient, &TranslationsRequestBody{Model: DefaultTranscriptionModel, Filename: "hi.mp3", Audio: strings.NewReader("audio")})
+			return err
+		},
+	}
+}
+
+func TestEndpointsErrorHandling(t *testing.T) {
+	client := MockClient{"https://fake.api.openai.com/v1"}
+	testCases := []struct {
+		name           string
+		httpClient     *MockResponseHTTPClient
+		expectedStatus int
+		expectedType   string
+		expectedMsg    string
+		// Endpoints that return the body unread, and so accept an empty body.
+		streaming []string
+	}{
+		{
+			name:           "Transport failure",
+			httpClient:     &MockResponseHTTPClient{err: errors.New("connection refused")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "cannot_send_request",
+			expectedMsg:    "connection refused",
+		},
+		{
+			name:           "JSON error body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusTooManyRequests, body: `{"error":{"message":"Rate limit reached","type":"requests"}}`},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedType:   "requests",
+			expectedMsg:    "Rate limit reached",
+		},
+		{
+			name:           "Non-JSON error body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusBadGateway, body: "<html>Bad Gateway</html>"},
+			expectedStatus: http.StatusBadGateway,
+			expectedType:   "http_error",
+			expectedMsg:    "<html>Bad Gateway</html>",
+		},
+		{
+			name:           "Empty error body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusServiceUnavailable},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedType:   "http_error",
+			expectedMsg:    http.StatusText(http.StatusServiceUnavailable),
+		},
+		{
+			name:           "Missing error body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusInternalServerError, nilBody: true},
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "http_error",
+			expectedMsg:    http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name:           "Empty success body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusOK},
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "empty_response_body",
+			streaming:      []string{"TextToSpeech", "FileContent", "StreamResponse", "StreamTextCompletion"},
+		},
+		{
+			name:           "Invalid success body",
+			httpClient:     &MockResponseHTTPClient{statusCode: http.StatusOK, body: "not json"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "cannot_decode_json",
+			streaming:      []string{"TextToSpeech", "FileContent", "StreamResponse", "StreamTextCompletion"},
+		},
+	}
+	for _, tc := range testCases {
+		for name, call := range testEndpoints() {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				err := call(client, tc.httpClient)
+				if contains(tc.streaming, name) {
+					if err != nil {
+						t.Fatalf("Expected no error, but got: %v", err)
+					}
+					return
+				}
+				if err == nil {
+					t.Fatal("Expected an error, but got nil")
+				}
+				if err.Status() != tc.expectedStatus {
+					t.Errorf("Expected status %d, got %d", tc.expectedStatus, err.Status())
+				}
+				if err.Err.Type != tc.expectedType {
+					t.Errorf("Expected type %s, got %s", tc.expectedType, err.Err.Type)
+				}
+				if tc.expectedMsg != "" && err.Error() != tc.expectedMsg {
+					t.Errorf("Expected message %q, got %q", tc.expectedMsg, err.Error())
+				}
+			})
+		}
+	}
+}
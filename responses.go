@@ -0,0 +1,378 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Response statuses.
+const (
+	ResponseStatusQueued     = "queued"
+	ResponseStatusInProgress = "in_progress"
+	ResponseStatusCompleted  = "completed"
+	ResponseStatusFailed     = "failed"
+	ResponseStatusCancelled  = "cancelled"
+	ResponseStatusIncomplete = "incomplete"
+)
+
+// Types of the items of responses.
+const (
+	ResponseItemMessage             = "message"
+	ResponseItemFunctionCall        = "function_call"
+	ResponseItemFunctionCallOutput  = "function_call_output"
+	ResponseItemReasoning           = "reasoning"
+	ResponseItemWebSearchCall       = "web_search_call"
+	ResponseItemFileSearchCall      = "file_search_call"
+	ResponseItemCodeInterpreterCall = "code_interpreter_call"
+	ResponseItemReference           = "item_reference"
+)
+
+// Reasoning summaries, from the shortest.
+const (
+	ReasoningSummaryAuto     = "auto"
+	ReasoningSummaryConcise  = "concise"
+	ReasoningSummaryDetailed = "detailed"
+)
+
+type (
+	// ResponseTool is a tool of a response: ResponseFunctionTool, WebSearchTool,
+	// ResponseFileSearchTool or CodeInterpreterTool.
+	ResponseTool interface {
+		responseTool()
+	}
+
+	// ResponseFunctionTool is a function defined as in chat completions. Strict
+	// enforces its parameters schema.
+	ResponseFunctionTool struct {
+		Function
+		Strict *bool `json:"strict,omitempty"`
+	}
+
+	// WebSearchTool lets the model search the web.
+	WebSearchTool struct {
+		// SearchContextSize is low, medium or high.
+		SearchContextSize string          `json:"search_context_size,omitempty"`
+		UserLocation      *UserLocation   `json:"user_location,omitempty"`
+		Filters           *WebSearchSites `json:"filters,omitempty"`
+	}
+
+	// UserLocation approximates the location of the user to localize searches.
+	UserLocation struct {
+		Type     string `json:"type"`
+		City     string `json:"city,omitempty"`
+		Country  string `json:"country,omitempty"`
+		Region   string `json:"region,omitempty"`
+		Timezone string `json:"timezone,omitempty"`
+	}
+
+	WebSearchSites struct {
+		AllowedDomains []string `json:"allowed_domains,omitempty"`
+	}
+
+	// ResponseFileSearchTool lets the model search vector stores.
+	ResponseFileSearchTool struct {
+		VectorStoreIds []string         `json:"vector_store_ids"`
+		MaxNumResults  int              `json:"max_num_results,omitempty"`
+		Filters        *AttributeFilter `json:"filters,omitempty"`
+		RankingOptions *RankingOptions  `json:"ranking_options,omitempty"`
+	}
+
+	// CodeInterpreterTool lets the model run Python code in a container.
+	CodeInterpreterTool struct {
+		// Container is the id of a container, or a CodeInterpreterContainer
+		// created for the response. It defaults to an automatic container.
+		Container any `json:"container"`
+	}
+
+	CodeInterpreterContainer struct {
+		Type    string   `json:"type"`
+		FileIds []string `json:"file_ids,omitempty"`
+	}
+
+	// ReasoningConfig configures reasoning models.
+	ReasoningConfig struct {
+		// Effort is minimal, low, medium or high.
+		Effort string `json:"effort,omitempty"`
+		// Summary asks for a summary of the reasoning, one of the
+		// ReasoningSummary constants.
+		Summary string `json:"summary,omitempty"`
+	}
+
+	CreateResponseRequest struct {
+		// Model defaults to DefaultChatModel.
+		Model string         `json:"model"`
+		Input []ResponseItem `json:"input"`
+		// Instructions are not carried over by PreviousResponseId.
+		Instructions string `json:"instructions,omitempty"`
+		// PreviousResponseId continues the conversation of a stored response,
+		// without sending its items again.
+		PreviousResponseId string            `json:"previous_response_id,omitempty"`
+		Tools              []ResponseTool    `json:"tools,omitempty"`
+		ToolChoice         any               `json:"tool_choice,omitempty"`
+		ParallelToolCalls  *bool             `json:"parallel_tool_calls,omitempty"`
+		Reasoning          *ReasoningConfig  `json:"reasoning,omitempty"`
+		MaxOutputTokens    int               `json:"max_output_tokens,omitempty"`
+		Temperature        *float64          `json:"temperature,omitempty"`
+		TopP               *float64          `json:"top_p,omitempty"`
+		Text               any               `json:"text,omitempty"`
+		Truncation         string            `json:"truncation,omitempty"`
+		Include            []string          `json:"include,omitempty"`
+		Store              *bool             `json:"store,omitempty"`
+		Metadata           map[string]string `json:"metadata,omitempty"`
+		Stream             bool              `json:"stream,omitempty"`
+	}
+
+	// ResponseItem is an input or output item of a response. The fields set
+	// depend on Type; output items can be sent back as input.
+	ResponseItem struct {
+		Type    string            `json:"type"`
+		Id      string            `json:"id,omitempty"`
+		Status  string            `json:"status,omitempty"`
+		Role    string            `json:"role,omitempty"`
+		Content []ResponseContent `json:"content,omitempty"`
+		// CallId, Name and Arguments describe a function call, and CallId and
+		// Output its output.
+		CallId    string `json:"call_id,omitempty"`
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+		Output    string `json:"output,omitempty"`
+		// Summary of a reasoning item.
+		Summary          []ResponseSummary `json:"summary,omitempty"`
+		EncryptedContent string            `json:"encrypted_content,omitempty"`
+		// Queries and Results of a file search call. Results are only set when
+		// the request includes file_search_call.results.
+		Queries []string                  `json:"queries,omitempty"`
+		Results []VectorStoreSearchResult `json:"results,omitempty"`
+		// Action of a web search call.
+		Action json.RawMessage `json:"action,omitempty"`
+		// Code run by a code interpreter call, with its outputs.
+		Code        string          `json:"code,omitempty"`
+		ContainerId string          `json:"container_id,omitempty"`
+		Outputs     json.RawMessage `json:"outputs,omitempty"`
+	}
+
+	// ResponseContent is a part of a message: input_text, input_image or
+	// input_file in input, output_text or refusal in output.
+	ResponseContent struct {
+		Type        string               `json:"type"`
+		Text        string               `json:"text,omitempty"`
+		Annotations []ResponseAnnotation `json:"annotations,omitempty"`
+		Refusal     string               `json:"refusal,omitempty"`
+		ImageURL    string               `json:"image_url,omitempty"`
+		FileId      string               `json:"file_id,omitempty"`
+		Detail      string               `json:"detail,omitempty"`
+	}
+
+	// ResponseAnnotation cites a file or a URL in output text.
+	ResponseAnnotation struct {
+		Type       string `json:"type"`
+		Index      int    `json:"index,omitempty"`
+		FileId     string `json:"file_id,omitempty"`
+		Filename   string `json:"filename,omitempty"`
+		URL        string `json:"url,omitempty"`
+		Title      string `json:"title,omitempty"`
+		StartIndex int    `json:"start_index,omitempty"`
+		EndIndex   int    `json:"end_index,omitempty"`
+	}
+
+	ResponseSummary struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+
+	// Response is a model response. Times are Unix timestamps in seconds.
+	Response struct {
+		Id                 string             `json:"id"`
+		Object             string             `json:"object"`
+		CreatedAt          int64              `json:"created_at"`
+		Status             string             `json:"status"`
+		Error              *JSONErr           `json:"error,omitempty"`
+		IncompleteDetails  *IncompleteDetails `json:"incomplete_details,omitempty"`
+		Model              string             `json:"model"`
+		Output             []ResponseItem     `json:"output"`
+		PreviousResponseId string             `json:"previous_response_id,omitempty"`
+		Reasoning          *ReasoningConfig   `json:"reasoning,omitempty"`
+		// Tools are kept encoded, as the tools of the request are interfaces.
+		Tools             []json.RawMessage `json:"tools,omitempty"`
+		ParallelToolCalls bool              `json:"parallel_tool_calls"`
+		Temperature       *float64          `json:"temperature,omitempty"`
+		TopP              *float64          `json:"top_p,omitempty"`
+		MaxOutputTokens   int               `json:"max_output_tokens,omitempty"`
+		Usage             *ResponseUsage    `json:"usage,omitempty"`
+		Metadata          map[string]string `json:"metadata,omitempty"`
+	}
+
+	ResponseUsage struct {
+		InputTokens        int `json:"input_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+		OutputTokens        int `json:"output_tokens"`
+		OutputTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+		TotalTokens int `json:"total_tokens"`
+	}
+)
+
+func (ResponseFunctionTool) responseTool()   {}
+func (WebSearchTool) responseTool()          {}
+func (ResponseFileSearchTool) responseTool() {}
+func (CodeInterpreterTool) responseTool()    {}
+
+// The tools are flattened in a JSON object with their type.
+
+func (t ResponseFunctionTool) MarshalJSON() ([]byte, error) {
+	type tool ResponseFunctionTool
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		tool
+	}{"function", tool(t)})
+}
+
+func (t WebSearchTool) MarshalJSON() ([]byte, error) {
+	type tool WebSearchTool
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		tool
+	}{"web_search", tool(t)})
+}
+
+func (t ResponseFileSearchTool) MarshalJSON() ([]byte, error) {
+	type tool ResponseFileSearchTool
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		tool
+	}{"file_search", tool(t)})
+}
+
+func (t CodeInterpreterTool) MarshalJSON() ([]byte, error) {
+	if t.Container == nil {
+		t.Container = CodeInterpreterContainer{Type: "auto"}
+	}
+	type tool CodeInterpreterTool
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		tool
+	}{"code_interpreter", tool(t)})
+}
+
+// ResponseFunctionTools converts chat completion tools to response tools.
+func ResponseFunctionTools(tools ...Tool) []ResponseTool {
+	responseTools := make([]ResponseTool, len(tools))
+	for i, tool := range tools {
+		responseTools[i] = ResponseFunctionTool{Function: tool.Function}
+	}
+	return responseTools
+}
+
+// InputMessage is a text message of the conversation.
+func InputMessage(role, text string) ResponseItem {
+	contentType := "input_text"
+	if role == "assistant" {
+		contentType = "output_text"
+	}
+	return ResponseItem{Type: ResponseItemMessage, Role: role, Content: []ResponseContent{{Type: contentType, Text: text}}}
+}
+
+// FunctionCallOutput is the output of the function call callId.
+func FunctionCallOutput(callId, output string) ResponseItem {
+	return ResponseItem{Type: ResponseItemFunctionCallOutput, CallId: callId, Output: output}
+}
+
+// OutputText returns the text of the output messages.
+func (r *Response) OutputText() string {
+	var text strings.Builder
+	for _, item := range r.Output {
+		if item.Type != ResponseItemMessage {
+			continue
+		}
+		for _, content := range item.Content {
+			if content.Type == "output_text" {
+				text.WriteString(content.Text)
+			}
+		}
+	}
+	return text.String()
+}
+
+// FunctionCalls returns the function calls of the output, to answer with
+// FunctionCallOutput items in the next request.
+func (r *Response) FunctionCalls() []ResponseItem {
+	var calls []ResponseItem
+	for _, item := range r.Output {
+		if item.Type == ResponseItemFunctionCall {
+			calls = append(calls, item)
+		}
+	}
+	return calls
+}
+
+// ReasoningSummary returns the summary of the reasoning of the model, when the
+// request asked for one.
+func (r *Response) ReasoningSummary() string {
+	var summaries []string
+	for _, item := range r.Output {
+		for _, summary := range item.Summary {
+			summaries = append(summaries, summary.Text)
+		}
+	}
+	return strings.Join(summaries, "\n\n")
+}
+
+func (body *CreateResponseRequest) withDefaults() (*CreateResponseRequest, *OpenAIErr) {
+	if len(body.Input) == 0 {
+		return nil, errInvalidRequest(errors.New("input is required"))
+	}
+	request := *body
+	if request.Model == "" {
+		request.Model = DefaultChatModel
+	}
+	if err := validateChatModel(request.Model, nil, nil); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	// The tools of a response are not chat Tools, so they are checked here.
+	if capabilities, ok := LookupModel(request.Model); ok && len(request.Tools) > 0 && !capabilities.Tools {
+		return nil, errInvalidRequest(fmt.Errorf("%s does not support tools", request.Model))
+	}
+	return &request, nil
+}
+
+// CreateResponse creates a model response. Chain responses with
+// PreviousResponseId, or by sending the output items back as input.
+func CreateResponse(api OpenAIClient, httpClient HTTPClient, body *CreateResponseRequest) (*Response, *OpenAIErr) {
+	request, err := body.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = false
+	res, err := postJSON(api, httpClient, "/responses", request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[Response](res)
+}
+
+func RetrieveResponse(api OpenAIClient, httpClient HTTPClient, responseId string) (*Response, *OpenAIErr) {
+	if responseId == "" {
+		return nil, errInvalidRequest(errors.New("response id is required"))
+	}
+	return getJSON[Response](api, httpClient, "/responses"+pathID(responseId))
+}
+
+func DeleteResponse(api OpenAIClient, httpClient HTTPDeleteClient, responseId string) (*Deletion, *OpenAIErr) {
+	if responseId == "" {
+		return nil, errInvalidRequest(errors.New("response id is required"))
+	}
+	return deleteJSON[Deletion](api, httpClient, "/responses"+pathID(responseId))
+}
+
+// ListResponseInputItems returns a page of the items a response was created from.
+func ListResponseInputItems(api OpenAIClient, httpClient HTTPClient, responseId string, params *ListParams) (*List[ResponseItem], *OpenAIErr) {
+	if responseId == "" {
+		return nil, errInvalidRequest(errors.New("response id is required"))
+	}
+	return getJSON[List[ResponseItem]](api, httpClient, "/responses"+pathID(responseId)+"/input_items", params.queryParams()...)
+}
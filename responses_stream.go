@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Types of the events streamed by StreamResponse.
+const (
+	ResponseEventCreated                    = "response.created"
+	ResponseEventInProgress                 = "response.in_progress"
+	ResponseEventCompleted                  = "response.completed"
+	ResponseEventFailed                     = "response.failed"
+	ResponseEventIncomplete                 = "response.incomplete"
+	ResponseEventOutputItemAdded            = "response.output_item.added"
+	ResponseEventOutputItemDone             = "response.output_item.done"
+	ResponseEventContentPartAdded           = "response.content_part.added"
+	ResponseEventContentPartDone            = "response.content_part.done"
+	ResponseEventOutputTextDelta            = "response.output_text.delta"
+	ResponseEventOutputTextDone             = "response.output_text.done"
+	ResponseEventOutputTextAnnotationAdded  = "response.output_text.annotation.added"
+	ResponseEventRefusalDelta               = "response.refusal.delta"
+	ResponseEventRefusalDone                = "response.refusal.done"
+	ResponseEventFunctionCallArgumentsDelta = "response.function_call_arguments.delta"
+	ResponseEventFunctionCallArgumentsDone  = "response.function_call_arguments.done"
+	ResponseEventReasoningSummaryPartAdded  = "response.reasoning_summary_part.added"
+	ResponseEventReasoningSummaryPartDone   = "response.reasoning_summary_part.done"
+	ResponseEventReasoningSummaryTextDelta  = "response.reasoning_summary_text.delta"
+	ResponseEventReasoningSummaryTextDone   = "response.reasoning_summary_text.done"
+	ResponseEventWebSearchCallInProgress    = "response.web_search_call.in_progress"
+	ResponseEventWebSearchCallSearching     = "response.web_search_call.searching"
+	ResponseEventWebSearchCallCompleted     = "response.web_search_call.completed"
+	ResponseEventFileSearchCallInProgress   = "response.file_search_call.in_progress"
+	ResponseEventFileSearchCallSearching    = "response.file_search_call.searching"
+	ResponseEventFileSearchCallCompleted    = "response.file_search_call.completed"
+	ResponseEventCodeInterpreterInProgress  = "response.code_interpreter_call.in_progress"
+	ResponseEventCodeInterpreterCodeDelta   = "response.code_interpreter_call_code.delta"
+	ResponseEventCodeInterpreterCodeDone    = "response.code_interpreter_call_code.done"
+	ResponseEventCodeInterpreterCompleted   = "response.code_interpreter_call.completed"
+	ResponseEventError                      = "error"
+)
+
+// ResponseEvent is an event streamed while a response is generated. The fields
+// set depend on Type: Response for the response.* lifecycle events, Item for
+// output items, Part for content and summary parts, Delta for the deltas, and
+// Text or Arguments when a text or function call is done.
+type ResponseEvent struct {
+	Type           string              `json:"type"`
+	SequenceNumber int                 `json:"sequence_number"`
+	Response       *Response           `json:"response,omitempty"`
+	OutputIndex    int                 `json:"output_index"`
+	ContentIndex   int                 `json:"content_index"`
+	SummaryIndex   int                 `json:"summary_index"`
+	ItemId         string              `json:"item_id,omitempty"`
+	Item           *ResponseItem       `json:"item,omitempty"`
+	Part           *ResponseContent    `json:"part,omitempty"`
+	Delta          string              `json:"delta,omitempty"`
+	Text           string              `json:"text,omitempty"`
+	Arguments      string              `json:"arguments,omitempty"`
+	Annotation     *ResponseAnnotation `json:"annotation,omitempty"`
+	// Code, Message and Param describe the error of an error event.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Param   string `json:"param,omitempty"`
+}
+
+// streamErr stops the stream on error events. Failed responses are not stream
+// errors: their response.failed event holds the error.
+func (e *ResponseEvent) streamErr() *OpenAIErr {
+	if e.Type != ResponseEventError {
+		return nil
+	}
+	return &OpenAIErr{Err: JSONErr{Message: e.Message, Type: "stream_error", Code: e.Code, Param: e.Param}, status: http.StatusInternalServerError, cause: errors.New(e.Message)}
+}
+
+// StreamResponse creates a model response and streams its events as they are
+// generated. The last event, response.completed, holds the whole response.
+func StreamResponse(api OpenAIClient, httpClient HTTPClient, body *CreateResponseRequest) (*Stream[ResponseEvent], *OpenAIErr) {
+	request, err := body.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+	return postStream[ResponseEvent](api, httpClient, "/responses", request)
+}
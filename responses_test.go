@@ -0,0 +1,173 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockResponsesHTTPClient answers the first request with a function call and
+// the requests continuing it with text, streamed when the request asks for it.
+type MockResponsesHTTPClient struct {
+	bodies []map[string]any
+	stream string
+}
+
+func (c *MockResponsesHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]any
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, err
+	}
+	c.bodies = append(c.bodies, body)
+	if body["stream"] == true {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/event-stream"}}, Body: io.NopCloser(strings.NewReader(c.stream))}, nil
+	}
+	if body["previous_response_id"] == nil {
+		return jsonResponse(http.StatusOK, `{"id":"resp_1","object":"response","status":"completed","model":"o4-mini","output":[
+			{"type":"reasoning","id":"rs_1","summary":[{"type":"summary_text","text":"The user wants the weather."}]},
+			{"type":"function_call","id":"fc_1","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Lisbon\"}","status":"completed"}
+		]}`), nil
+	}
+	return jsonResponse(http.StatusOK, `{"id":"resp_2","object":"response","status":"completed","model":"o4-mini","previous_response_id":"resp_1","output":[
+		{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"It is 21°C ","annotations":[]},{"type":"output_text","text":"in Lisbon.","annotations":[{"type":"url_citation","url":"https://weather.example","title":"Weather","start_index":0,"end_index":10}]}]}
+	],"usage":{"input_tokens":20,"input_tokens_details":{"cached_tokens":0},"output_tokens":8,"output_tokens_details":{"reasoning_tokens":4},"total_tokens":28}}`), nil
+}
+
+func (c *MockResponsesHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func TestResponsesChaining(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockResponsesHTTPClient{}
+	weather := Tool{Type: "function", Function: Function{Name: "get_weather", Description: "Current weather of a city"}}
+	tools := append(ResponseFunctionTools(weather),
+		WebSearchTool{SearchContextSize: "low"},
+		ResponseFileSearchTool{VectorStoreIds: []string{"vs_1"}, Filters: FilterEq("lang", "en")},
+		CodeInterpreterTool{},
+	)
+	response, err := CreateResponse(api, httpClient, &CreateResponseRequest{
+		Model:     "o4-mini",
+		Input:     []ResponseItem{InputMessage("user", "Weather in Lisbon?")},
+		Tools:     tools,
+		Reasoning: &ReasoningConfig{Effort: "low", Summary: ReasoningSummaryAuto},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(httpClient.bodies[0]["tools"])
+	if want := `[{"description":"Current weather of a city","name":"get_weather","parameters":{"properties":null,"type":""},"type":"function"},{"search_context_size":"low","type":"web_search"},{"filters":{"key":"lang","type":"eq","value":"en"},"type":"file_search","vector_store_ids":["vs_1"]},{"container":{"type":"auto"},"type":"code_interpreter"}]`; string(b) != want {
+		t.Errorf("expected tools %s, got %s", want, b)
+	}
+	b, _ = json.Marshal(httpClient.bodies[0]["input"])
+	if want := `[{"content":[{"text":"Weather in Lisbon?","type":"input_text"}],"role":"user","type":"message"}]`; string(b) != want {
+		t.Errorf("expected input %s, got %s", want, b)
+	}
+	if response.ReasoningSummary() != "The user wants the weather." {
+		t.Errorf("unexpected reasoning summary %q", response.ReasoningSummary())
+	}
+	calls := response.FunctionCalls()
+	if len(calls) != 1 || calls[0].Name != "get_weather" || calls[0].Arguments != `{"city":"Lisbon"}` {
+		t.Fatalf("unexpected function calls %+v", calls)
+	}
+
+	response, err = CreateResponse(api, httpClient, &CreateResponseRequest{
+		Model:              "o4-mini",
+		PreviousResponseId: response.Id,
+		Input:              []ResponseItem{FunctionCallOutput(calls[0].CallId, `{"celsius":21}`)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ = json.Marshal(httpClient.bodies[1])
+	if want := `{"input":[{"call_id":"call_1","output":"{\"celsius\":21}","type":"function_call_output"}],"model":"o4-mini","previous_response_id":"resp_1"}`; string(b) != want {
+		t.Errorf("expected %s, got %s", want, b)
+	}
+	if response.OutputText() != "It is 21°C in Lisbon." || response.Usage.OutputTokensDetails.ReasoningTokens != 4 {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if annotation := response.Output[0].Content[1].Annotations[0]; annotation.URL != "https://weather.example" {
+		t.Errorf("unexpected annotation %+v", annotation)
+	}
+
+	if _, err := CreateResponse(api, httpClient, &CreateResponseRequest{Model: "gpt-4o-mini"}); err == nil {
+		t.Error("expected an error without input")
+	}
+	if _, err := CreateResponse(api, httpClient, &CreateResponseRequest{Model: "text-embedding-3-small", Input: []ResponseItem{InputMessage("user", "Hi")}}); err == nil {
+		t.Error("expected an error for an embedding model")
+	}
+	sent := len(httpClient.bodies)
+	if _, err := CreateResponse(api, httpClient, &CreateResponseRequest{Model: "o1-mini", Input: []ResponseItem{InputMessage("user", "Hi")}, Tools: []ResponseTool{WebSearchTool{}}}); err == nil || err.Status() != http.StatusBadRequest {
+		t.Errorf("expected a bad request for tools with a model without tools, got %v", err)
+	}
+	if len(httpClient.bodies) != sent {
+		t.Error("expected the request with unsupported tools not to be sent")
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockResponsesHTTPClient{stream: strings.Join([]string{
+		"event: response.created\ndata: {\"type\":\"response.created\",\"sequence_number\":0,\"response\":{\"id\":\"resp_1\",\"status\":\"in_progress\",\"output\":[]}}\n",
+		": keep-alive\n",
+		"event: response.reasoning_summary_text.delta\ndata: {\"type\":\"response.reasoning_summary_text.delta\",\"item_id\":\"rs_1\",\"summary_index\":0,\"delta\":\"Thinking\"}\n",
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"output_index\":1,\"item\":{\"type\":\"message\",\"id\":\"msg_1\",\"role\":\"assistant\",\"content\":[]}}\n",
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"item_id\":\"msg_1\",\"output_index\":1,\"delta\":\"Hello\"}\n",
+		"event: response.output_text.delta\r\ndata: {\"type\":\"response.output_text.delta\",\"item_id\":\"msg_1\",\"output_index\":1,\"delta\":\", world\"}\r\n",
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hello, world\"}]}]}}",
+	}, "\n")}
+	stream, err := StreamResponse(api, httpClient, &CreateResponseRequest{Model: "o4-mini", Input: []ResponseItem{InputMessage("user", "Hi")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+	var types []string
+	var text, summary string
+	var completed *Response
+	for stream.Next() {
+		event := stream.Current()
+		types = append(types, event.Type)
+		switch event.Type {
+		case ResponseEventOutputTextDelta:
+			text += event.Delta
+		case ResponseEventReasoningSummaryTextDelta:
+			summary += event.Delta
+		case ResponseEventCompleted:
+			completed = event.Response
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if httpClient.bodies[0]["stream"] != true {
+		t.Error("expected the request to ask for a stream")
+	}
+	if len(types) != 6 || text != "Hello, world" || summary != "Thinking" {
+		t.Errorf("unexpected events %v, text %q, summary %q", types, text, summary)
+	}
+	if completed == nil || completed.OutputText() != "Hello, world" {
+		t.Errorf("unexpected completed response %+v", completed)
+	}
+
+	httpClient.stream = "data: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_1\"}}\n\ndata: {\"type\":\"error\",\"code\":\"server_error\",\"message\":\"The server had an error\"}\n\n"
+	stream, err = StreamResponse(api, httpClient, &CreateResponseRequest{Input: []ResponseItem{InputMessage("user", "Hi")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+	events := 0
+	for stream.Next() {
+		events++
+	}
+	if err := stream.Err(); events != 1 || err == nil || err.Err.Code != "server_error" || err.Error() != "The server had an error" {
+		t.Errorf("expected the stream to fail after 1 event, got %d events and %v", events, err)
+	}
+}
@@ -0,0 +1,330 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Run statuses.
+const (
+	RunStatusQueued         = "queued"
+	RunStatusInProgress     = "in_progress"
+	RunStatusRequiresAction = "requires_action"
+	RunStatusCancelling     = "cancelling"
+	RunStatusCancelled      = "cancelled"
+	RunStatusFailed         = "failed"
+	RunStatusCompleted      = "completed"
+	RunStatusIncomplete     = "incomplete"
+	RunStatusExpired        = "expired"
+)
+
+type (
+	// CreateRunRequest runs an assistant on a thread. The fields left empty
+	// use the settings of the assistant.
+	CreateRunRequest struct {
+		AssistantId            string                 `json:"assistant_id"`
+		Model                  string                 `json:"model,omitempty"`
+		Instructions           string                 `json:"instructions,omitempty"`
+		AdditionalInstructions string                 `json:"additional_instructions,omitempty"`
+		AdditionalMessages     []CreateMessageRequest `json:"additional_messages,omitempty"`
+		Tools                  []AssistantTool        `json:"tools,omitempty"`
+		Metadata               map[string]string      `json:"metadata,omitempty"`
+		Temperature            *float64               `json:"temperature,omitempty"`
+		TopP                   *float64               `json:"top_p,omitempty"`
+		MaxPromptTokens        int                    `json:"max_prompt_tokens,omitempty"`
+		MaxCompletionTokens    int                    `json:"max_completion_tokens,omitempty"`
+		TruncationStrategy     *TruncationStrategy    `json:"truncation_strategy,omitempty"`
+		// ToolChoice is none, auto, required, or a tool such as
+		// {"type": "function", "function": {"name": "my_function"}}.
+		ToolChoice        any   `json:"tool_choice,omitempty"`
+		ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+		ResponseFormat    any   `json:"response_format,omitempty"`
+	}
+
+	// CreateThreadAndRunRequest creates a thread and runs an assistant on it.
+	CreateThreadAndRunRequest struct {
+		CreateRunRequest
+		Thread        *CreateThreadRequest `json:"thread,omitempty"`
+		ToolResources *ToolResources       `json:"tool_resources,omitempty"`
+	}
+
+	// TruncationStrategy selects the messages of the thread sent to the model.
+	TruncationStrategy struct {
+		// Type is auto or last_messages.
+		Type         string `json:"type"`
+		LastMessages int    `json:"last_messages,omitempty"`
+	}
+
+	// Run is an execution of an assistant on a thread. Times are Unix
+	// timestamps in seconds.
+	Run struct {
+		Id                  string              `json:"id"`
+		Object              string              `json:"object"`
+		CreatedAt           int64               `json:"created_at"`
+		ThreadId            string              `json:"thread_id"`
+		AssistantId         string              `json:"assistant_id"`
+		Status              string              `json:"status"`
+		RequiredAction      *RequiredAction     `json:"required_action,omitempty"`
+		LastError           *RunError           `json:"last_error,omitempty"`
+		ExpiresAt           int64               `json:"expires_at,omitempty"`
+		StartedAt           int64               `json:"started_at,omitempty"`
+		CancelledAt         int64               `json:"cancelled_at,omitempty"`
+		FailedAt            int64               `json:"failed_at,omitempty"`
+		CompletedAt         int64               `json:"completed_at,omitempty"`
+		IncompleteDetails   *IncompleteDetails  `json:"incomplete_details,omitempty"`
+		Model               string              `json:"model"`
+		Instructions        string              `json:"instructions"`
+		Tools               []AssistantTool     `json:"tools"`
+		Metadata            map[string]string   `json:"metadata,omitempty"`
+		Usage               *Usage              `json:"usage,omitempty"`
+		Temperature         *float64            `json:"temperature,omitempty"`
+		TopP                *float64            `json:"top_p,omitempty"`
+		MaxPromptTokens     int                 `json:"max_prompt_tokens,omitempty"`
+		MaxCompletionTokens int                 `json:"max_completion_tokens,omitempty"`
+		TruncationStrategy  *TruncationStrategy `json:"truncation_strategy,omitempty"`
+		ParallelToolCalls   bool                `json:"parallel_tool_calls"`
+	}
+
+	// RequiredAction lists the tool calls whose outputs the run waits for.
+	RequiredAction struct {
+		Type              string `json:"type"`
+		SubmitToolOutputs struct {
+			ToolCalls []ToolCall `json:"tool_calls"`
+		} `json:"submit_tool_outputs"`
+	}
+
+	RunError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	IncompleteDetails struct {
+		Reason string `json:"reason"`
+	}
+
+	// ToolOutput is the result of a tool call, submitted to a run.
+	ToolOutput struct {
+		ToolCallId string `json:"tool_call_id"`
+		Output     string `json:"output"`
+	}
+
+	SubmitToolOutputsRequest struct {
+		ToolOutputs []ToolOutput `json:"tool_outputs"`
+	}
+
+	// RunStep is a step of a run: creating a message or calling tools.
+	RunStep struct {
+		Id          string         `json:"id"`
+		Object      string         `json:"object"`
+		CreatedAt   int64          `json:"created_at"`
+		RunId       string         `json:"run_id"`
+		AssistantId string         `json:"assistant_id"`
+		ThreadId    string         `json:"thread_id"`
+		Type        string         `json:"type"`
+		Status      string         `json:"status"`
+		StepDetails RunStepDetails `json:"step_details"`
+		LastError   *RunError      `json:"last_error,omitempty"`
+		CompletedAt int64          `json:"completed_at,omitempty"`
+		Usage       *Usage         `json:"usage,omitempty"`
+	}
+
+	// RunStepDetails holds MessageCreation or ToolCalls, depending on Type.
+	RunStepDetails struct {
+		Type            string `json:"type"`
+		MessageCreation *struct {
+			MessageId string `json:"message_id"`
+		} `json:"message_creation,omitempty"`
+		ToolCalls []RunStepToolCall `json:"tool_calls,omitempty"`
+	}
+
+	// RunStepToolCall is a tool called during a run step, with its output.
+	RunStepToolCall struct {
+		Id       string `json:"id"`
+		Type     string `json:"type"`
+		Function *struct {
+			FunctionCall
+			Output *string `json:"output"`
+		} `json:"function,omitempty"`
+		CodeInterpreter *struct {
+			Input   string `json:"input"`
+			Outputs []struct {
+				Type  string `json:"type"`
+				Logs  string `json:"logs,omitempty"`
+				Image *struct {
+					FileId string `json:"file_id"`
+				} `json:"image,omitempty"`
+			} `json:"outputs"`
+		} `json:"code_interpreter,omitempty"`
+		FileSearch *FileSearchTool `json:"file_search,omitempty"`
+	}
+)
+
+// Done reports whether the run reached a final status.
+func (r *Run) Done() bool {
+	switch r.Status {
+	case RunStatusCancelled, RunStatusFailed, RunStatusCompleted, RunStatusIncomplete, RunStatusExpired:
+		return true
+	}
+	return false
+}
+
+func runPath(threadId, runId string) string {
+	return "/threads" + pathID(threadId) + "/runs" + pathID(runId)
+}
+
+func CreateRun(api OpenAIClient, httpClient HTTPClient, threadId string, body *CreateRunRequest) (*Run, *OpenAIErr) {
+	if threadId == "" || body.AssistantId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and assistant_id are required"))
+	}
+	return postAssistants[Run](api, httpClient, "/threads"+pathID(threadId)+"/runs", body)
+}
+
+func CreateThreadAndRun(api OpenAIClient, httpClient HTTPClient, body *CreateThreadAndRunRequest) (*Run, *OpenAIErr) {
+	if body.AssistantId == "" {
+		return nil, errInvalidRequest(errors.New("assistant_id is required"))
+	}
+	return postAssistants[Run](api, httpClient, "/threads/runs", body)
+}
+
+func ListRuns(api OpenAIClient, httpClient HTTPClient, threadId string, params *ListParams) (*List[Run], *OpenAIErr) {
+	if threadId == "" {
+		return nil, errInvalidRequest(errors.New("thread id is required"))
+	}
+	return getJSON[List[Run]](assistantsAPI(api), httpClient, "/threads"+pathID(threadId)+"/runs", params.queryParams()...)
+}
+
+func RetrieveRun(api OpenAIClient, httpClient HTTPClient, threadId, runId string) (*Run, *OpenAIErr) {
+	if threadId == "" || runId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and run id are required"))
+	}
+	return getJSON[Run](assistantsAPI(api), httpClient, runPath(threadId, runId))
+}
+
+// ModifyRun replaces the metadata of a run.
+func ModifyRun(api OpenAIClient, httpClient HTTPClient, threadId, runId string, metadata map[string]string) (*Run, *OpenAIErr) {
+	if threadId == "" || runId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and run id are required"))
+	}
+	return postAssistants[Run](api, httpClient, runPath(threadId, runId), &ModifyRequest{Metadata: metadata})
+}
+
+func CancelRun(api OpenAIClient, httpClient HTTPClient, threadId, runId string) (*Run, *OpenAIErr) {
+	if threadId == "" || runId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and run id are required"))
+	}
+	return postAssistants[Run](api, httpClient, runPath(threadId, runId)+"/cancel", struct{}{})
+}
+
+// SubmitToolOutputs sends the outputs of the tool calls of a run in the
+// requires_action status. Every call of the required action must be answered
+// at once.
+func SubmitToolOutputs(api OpenAIClient, httpClient HTTPClient, threadId, runId string, outputs []ToolOutput) (*Run, *OpenAIErr) {
+	if threadId == "" || runId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and run id are required"))
+	}
+	return postAssistants[Run](api, httpClient, runPath(threadId, runId)+"/submit_tool_outputs", &SubmitToolOutputsRequest{ToolOutputs: outputs})
+}
+
+func ListRunSteps(api OpenAIClient, httpClient HTTPClient, threadId, runId string, params *ListParams) (*List[RunStep], *OpenAIErr) {
+	if threadId == "" || runId == "" {
+		return nil, errInvalidRequest(errors.New("thread id and run id are required"))
+	}
+	return getJSON[List[RunStep]](assistantsAPI(api), httpClient, runPath(threadId, runId)+"/steps", params.queryParams()...)
+}
+
+func RetrieveRunStep(api OpenAIClient, httpClient HTTPClient, threadId, runId, stepId string) (*RunStep, *OpenAIErr) {
+	if threadId == "" || runId == "" || stepId == "" {
+		return nil, errInvalidRequest(errors.New("thread id, run id and step id are required"))
+	}
+	return getJSON[RunStep](assistantsAPI(api), httpClient, runPath(threadId, runId)+"/steps"+pathID(stepId))
+}
+
+// ToolHandler runs a function called by an assistant with its arguments encoded
+// in JSON, and returns the output sent back to the run.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// RunPollOpts configures WaitRun. Zero values use the defaults.
+type RunPollOpts struct {
+	// Interval is the first delay between two polls, 500ms by default. It
+	// doubles after every poll up to MaxInterval, 5s by default.
+	Interval, MaxInterval time.Duration
+	// MaxRetries bounds the consecutive failed polls, 5 by default.
+	MaxRetries int
+	// Handlers run the function tool calls, by function name. The error of a
+	// handler is sent to the run as the output of the call, so the assistant
+	// can recover from it.
+	Handlers map[string]ToolHandler
+	// OnPoll, when set, is called with every run status received.
+	OnPoll func(*Run)
+}
+
+// Polling defaults of WaitRun. Runs finish in seconds, unlike batches.
+const (
+	defaultRunPollInterval    = 500 * time.Millisecond
+	defaultRunPollMaxInterval = 5 * time.Second
+)
+
+// WaitRun polls a run until it reaches a final status, and answers the tool
+// calls it requires with opts.Handlers meanwhile. A tool call without handler
+// returns an error of type "tool_handler_missing" and leaves the run waiting.
+// The final run is returned whatever its status. Canceling ctx stops waiting,
+// not the run.
+func WaitRun(ctx context.Context, api OpenAIClient, httpClient HTTPClient, threadId, runId string, opts *RunPollOpts) (*Run, *OpenAIErr) {
+	if opts == nil {
+		opts = &RunPollOpts{}
+	}
+	interval, maxInterval := opts.Interval, opts.MaxInterval
+	if interval <= 0 {
+		interval = defaultRunPollInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultRunPollMaxInterval
+	}
+	fetch := func() (*Run, *OpenAIErr) {
+		return RetrieveRun(api, httpClient, threadId, runId)
+	}
+	for {
+		run, err := poll(ctx, interval, maxInterval, opts.MaxRetries, fetch, func(run *Run) bool {
+			if opts.OnPoll != nil {
+				opts.OnPoll(run)
+			}
+			return run.Done() || run.Status == RunStatusRequiresAction
+		})
+		if err != nil {
+			return nil, err
+		}
+		if run.Done() {
+			return run, nil
+		}
+		outputs, err := runTools(ctx, run, opts.Handlers)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := SubmitToolOutputs(api, httpClient, threadId, runId, outputs); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// runTools calls the handlers of the tool calls required by run.
+func runTools(ctx context.Context, run *Run, handlers map[string]ToolHandler) ([]ToolOutput, *OpenAIErr) {
+	if run.RequiredAction == nil {
+		return nil, errInvalidRequest(fmt.Errorf("run %s requires an action but does not describe it", run.Id))
+	}
+	calls := run.RequiredAction.SubmitToolOutputs.ToolCalls
+	outputs := make([]ToolOutput, len(calls))
+	for i, call := range calls {
+		handler, ok := handlers[call.Function.Name]
+		if !ok {
+			return nil, invalidRequestError(fmt.Errorf("no handler for function %s called by run %s", call.Function.Name, run.Id), "tool_handler_missing")
+		}
+		output, err := handler(ctx, call.Function.Args)
+		if err != nil {
+			output = "error: " + err.Error()
+		}
+		outputs[i] = ToolOutput{ToolCallId: call.Id, Output: output}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxStreamEvent bounds the size of an event of a stream.
+const maxStreamEvent = 16 << 20
+
+// Stream reads the events of a streamed response, sent as server-sent events,
+// as they arrive:
+//
+//	defer stream.Close()
+//	for stream.Next() {
+//		event := stream.Current()
+//	}
+//	if err := stream.Err(); err != nil {
+//	}
+//
+// A Stream is not safe for concurrent use.
+type Stream[T any] struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	current *T
+	err     *OpenAIErr
+}
+
+// streamFailure is implemented by the events that report an error in a stream.
+type streamFailure interface {
+	streamErr() *OpenAIErr
+}
+
+func newStream[T any](body io.ReadCloser) *Stream[T] {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxStreamEvent)
+	return &Stream[T]{body: body, scanner: scanner}
+}
+
+// postStream sends body as JSON to path and returns the stream of the response.
+func postStream[T any](api OpenAIClient, httpClient HTTPClient, path string, body any) (*Stream[T], *OpenAIErr) {
+	res, err := postJSON(api, httpClient, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return newStream[T](res.Body), nil
+}
+
+// Next reads the next event, and returns false at the end of the stream or
+// when an error occurred, reported by Err.
+func (s *Stream[T]) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	data, ok := s.nextData()
+	if !ok {
+		return false
+	}
+	if bytes.Equal(data, []byte("[DONE]")) {
+		return false
+	}
+	var failure struct {
+		Error *JSONErr `json:"error"`
+	}
+	if json.Unmarshal(data, &failure) == nil && failure.Error != nil && failure.Error.Message != "" {
+		s.err = &OpenAIErr{Err: *failure.Error, status: http.StatusInternalServerError}
+		return false
+	}
+	event := new(T)
+	if err := json.Unmarshal(data, event); err != nil {
+		s.err = errCannotDecodeJSON(err)
+		return false
+	}
+	if failed, ok := any(event).(streamFailure); ok {
+		if s.err = failed.streamErr(); s.err != nil {
+			return false
+		}
+	}
+	s.current = event
+	return true
+}
+
+// nextData returns the data of the next event, the data lines of which are
+// joined by newlines. Comments and the other fields are skipped.
+func (s *Stream[T]) nextData() ([]byte, bool) {
+	var data []byte
+	for s.scanner.Scan() {
+		line := s.scanner.Bytes()
+		if len(line) == 0 {
+			if data != nil {
+				return data, true
+			}
+			continue
+		}
+		value, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		value = bytes.TrimPrefix(value, []byte(" "))
+		if data != nil {
+			data = append(data, '\n')
+		}
+		data = append(data, value...)
+	}
+	if err := s.scanner.Err(); err != nil {
+		s.err = errCannotReadBody(err)
+		return nil, false
+	}
+	// The last event may not be followed by a blank line.
+	return data, data != nil
+}
+
+// Current returns the event read by the last call to Next.
+func (s *Stream[T]) Current() *T {
+	return s.current
+}
+
+// Err returns the error that stopped the stream, nil at the end of a stream
+// that completed. Errors sent by the API in the stream have status 500.
+func (s *Stream[T]) Err() *OpenAIErr {
+	return s.err
+}
+
+// Close closes the response. Closing a stream before its end cancels it.
+func (s *Stream[T]) Close() error {
+	return s.body.Close()
+}
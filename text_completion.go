@@ -0,0 +1,151 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultTextCompletionModel is the model of the text completions that do not set one.
+const DefaultTextCompletionModel = "gpt-3.5-turbo-instruct"
+
+// MaxTextCompletionLogprobs is the maximum number of most likely tokens whose
+// log probabilities a text completion returns.
+const MaxTextCompletionLogprobs = 5
+
+type (
+	// TextCompletionRequest is a request to the legacy completions endpoint,
+	// which completes a raw prompt instead of a conversation.
+	TextCompletionRequest struct {
+		Model string `json:"model"`
+		// Prompt is a string, an array of strings, an array of tokens or an
+		// array of token arrays.
+		Prompt any `json:"prompt,omitempty"`
+		// Suffix is the text following the completion, for insertions.
+		Suffix string `json:"suffix,omitempty"`
+		// Echo returns the prompt in addition to the completion.
+		Echo bool `json:"echo,omitempty"`
+		// Logprobs is the number of most likely tokens returned with their log
+		// probabilities for every token, up to MaxTextCompletionLogprobs.
+		Logprobs *int `json:"logprobs,omitempty"`
+		// BestOf generates BestOf completions and returns the N best ones.
+		BestOf           int            `json:"best_of,omitempty"`
+		N                int            `json:"n,omitempty"`
+		MaxTokens        *int           `json:"max_tokens,omitempty"`
+		Temperature      *float64       `json:"temperature,omitempty"`
+		TopP             *float64       `json:"top_p,omitempty"`
+		FrequencyPenalty float64        `json:"frequency_penalty,omitempty"`
+		PresencePenalty  float64        `json:"presence_penalty,omitempty"`
+		LogitBias        map[string]int `json:"logit_bias,omitempty"`
+		Seed             *int           `json:"seed,omitempty"`
+		// Stop is a string or an array of up to 4 strings.
+		Stop          any            `json:"stop,omitempty"`
+		User          string         `json:"user,omitempty"`
+		Stream        bool           `json:"stream,omitempty"`
+		StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	}
+
+	// StreamOptions configures streamed responses. IncludeUsage sends the usage
+	// of the request in a last event without choices.
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	}
+
+	// TextCompletionResponse is a text completion, or an event of a streamed one.
+	TextCompletionResponse struct {
+		ID                string       `json:"id"`
+		Object            string       `json:"object"`
+		Created           int64        `json:"created"`
+		Model             string       `json:"model"`
+		SystemFingerprint string       `json:"system_fingerprint,omitempty"`
+		Choices           []TextChoice `json:"choices"`
+		Usage             Usage        `json:"usage"`
+	}
+
+	// TextChoice is a completion of a prompt. The choices of a request with
+	// several prompts are ordered by prompt, N choices each.
+	TextChoice struct {
+		Index        int           `json:"index"`
+		Text         string        `json:"text"`
+		Logprobs     *TextLogprobs `json:"logprobs"`
+		FinishReason string        `json:"finish_reason"`
+	}
+
+	// TextLogprobs holds the log probabilities of the tokens of a choice. The
+	// first token of an echoed prompt has no log probability: its TokenLogprobs
+	// is 0 and its TopLogprobs nil.
+	TextLogprobs struct {
+		Tokens        []string             `json:"tokens"`
+		TokenLogprobs []float64            `json:"token_logprobs"`
+		TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+		TextOffset    []int                `json:"text_offset"`
+	}
+)
+
+// Text returns the text of the first choice.
+func (r *TextCompletionResponse) Text() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Text
+}
+
+// withDefaults returns the request with its default model, streamed or not,
+// or an error when its parameters are invalid.
+func (r *TextCompletionRequest) withDefaults(stream bool) (*TextCompletionRequest, *OpenAIErr) {
+	request := *r
+	if request.Model == "" {
+		request.Model = DefaultTextCompletionModel
+	}
+	request.Stream = stream
+	if !stream {
+		request.StreamOptions = nil
+	}
+	if err := request.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	return &request, nil
+}
+
+func (r *TextCompletionRequest) validate() error {
+	if capabilities, ok := LookupModel(r.Model); ok && !capabilities.TextCompletions {
+		return fmt.Errorf("%s does not support text completions", r.Model)
+	}
+	if r.Logprobs != nil && (*r.Logprobs < 0 || *r.Logprobs > MaxTextCompletionLogprobs) {
+		return fmt.Errorf("logprobs must be between 0 and %d, got %d", MaxTextCompletionLogprobs, *r.Logprobs)
+	}
+	if r.BestOf > 0 {
+		if r.BestOf < max(r.N, 1) {
+			return fmt.Errorf("best_of (%d) must be greater than or equal to n (%d)", r.BestOf, r.N)
+		}
+		if r.Stream && r.BestOf > 1 {
+			return errors.New("best_of cannot be used with stream")
+		}
+	}
+	return nil
+}
+
+// TextCompletion completes a prompt with the legacy completions endpoint,
+// used by instruct and base models such as gpt-3.5-turbo-instruct. The model
+// defaults to DefaultTextCompletionModel, and models known not to support
+// text completions, according to LookupModel, fail without being sent.
+func TextCompletion(api OpenAIClient, httpClient HTTPClient, body *TextCompletionRequest) (*TextCompletionResponse, *OpenAIErr) {
+	request, err := body.withDefaults(false)
+	if err != nil {
+		return nil, err
+	}
+	res, err := postJSON(api, httpClient, "/completions", request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[TextCompletionResponse](res)
+}
+
+// StreamTextCompletion completes a prompt and streams the text of the choices
+// as it is generated, each event holding the next part of one or more choices.
+func StreamTextCompletion(api OpenAIClient, httpClient HTTPClient, body *TextCompletionRequest) (*Stream[TextCompletionResponse], *OpenAIErr) {
+	request, err := body.withDefaults(true)
+	if err != nil {
+		return nil, err
+	}
+	return postStream[TextCompletionResponse](api, httpClient, "/completions", request)
+}
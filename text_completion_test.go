@@ -0,0 +1,124 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockTextCompletionHTTPClient answers with an echoed completion, streamed
+// when the request asks for it.
+type MockTextCompletionHTTPClient struct {
+	bodies []string
+}
+
+func (c *MockTextCompletionHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.bodies = append(c.bodies, string(b))
+	if strings.Contains(string(b), `"stream":true`) {
+		stream := strings.Join([]string{
+			`data: {"id":"cmpl-1","object":"text_completion","model":"gpt-3.5-turbo-instruct","choices":[{"index":0,"text":"This","logprobs":null,"finish_reason":null}]}`,
+			`data: {"id":"cmpl-1","object":"text_completion","model":"gpt-3.5-turbo-instruct","choices":[{"index":0,"text":" is a test.","logprobs":null,"finish_reason":"stop"}]}`,
+			`data: {"id":"cmpl-1","object":"text_completion","model":"gpt-3.5-turbo-instruct","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`,
+			`data: [DONE]`,
+		}, "\n\n")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/event-stream"}}, Body: io.NopCloser(strings.NewReader(stream))}, nil
+	}
+	return jsonResponse(http.StatusOK, `{"id":"cmpl-1","object":"text_completion","created":1700000000,"model":"gpt-3.5-turbo-instruct","choices":[
+		{"index":0,"text":"Say this is a test\n\nThis is a test.","finish_reason":"stop","logprobs":{
+			"tokens":["Say"," this","\n\n","This"],
+			"token_logprobs":[null,-2.5,-0.1,-0.01],
+			"top_logprobs":[null,{" this":-2.5," hello":-1.2},{"\n\n":-0.1},{"This":-0.01}],
+			"text_offset":[0,3,18,20]
+		}}
+	],"usage":{"prompt_tokens":5,"completion_tokens":6,"total_tokens":11}}`), nil
+}
+
+func (c *MockTextCompletionHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func TestTextCompletion(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockTextCompletionHTTPClient{}
+	logprobs, maxTokens := 2, 16
+	completion, err := TextCompletion(api, httpClient, &TextCompletionRequest{
+		Prompt:        "Say this is a test",
+		Echo:          true,
+		Logprobs:      &logprobs,
+		MaxTokens:     &maxTokens,
+		Stop:          []string{"\n\n\n"},
+		LogitBias:     map[string]int{"50256": -100},
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"model":"gpt-3.5-turbo-instruct","prompt":"Say this is a test","echo":true,"logprobs":2,"max_tokens":16,"logit_bias":{"50256":-100},"stop":["\n\n\n"]}`; httpClient.bodies[0] != want {
+		t.Errorf("expected %s, got %s", want, httpClient.bodies[0])
+	}
+	if completion.Text() != "Say this is a test\n\nThis is a test." || completion.Usage.TotalTokens != 11 {
+		t.Errorf("unexpected completion %+v", completion)
+	}
+	lp := completion.Choices[0].Logprobs
+	if lp == nil || len(lp.Tokens) != 4 || lp.TokenLogprobs[0] != 0 || lp.TokenLogprobs[1] != -2.5 || lp.TopLogprobs[0] != nil || lp.TopLogprobs[1][" hello"] != -1.2 || lp.TextOffset[3] != 20 {
+		t.Errorf("unexpected logprobs %+v", lp)
+	}
+
+	stream, err := StreamTextCompletion(api, httpClient, &TextCompletionRequest{Model: "davinci-002", Prompt: []string{"Say this is a test"}, Suffix: "\nDone.", StreamOptions: &StreamOptions{IncludeUsage: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+	var text string
+	var usage Usage
+	for stream.Next() {
+		event := stream.Current()
+		for _, choice := range event.Choices {
+			text += choice.Text
+		}
+		usage = event.Usage
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body map[string]any
+	json.Unmarshal([]byte(httpClient.bodies[1]), &body)
+	if body["stream"] != true || body["suffix"] != "\nDone." || body["stream_options"] == nil {
+		t.Errorf("unexpected stream request %s", httpClient.bodies[1])
+	}
+	if text != "This is a test." || usage.TotalTokens != 10 {
+		t.Errorf("unexpected streamed text %q and usage %+v", text, usage)
+	}
+}
+
+func TestTextCompletionValidation(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockTextCompletionHTTPClient{}
+	six := 6
+	for _, request := range []*TextCompletionRequest{
+		{Model: "gpt-4o-mini", Prompt: "Hi"},
+		{Prompt: "Hi", Logprobs: &six},
+		{Prompt: "Hi", BestOf: 2, N: 3},
+	} {
+		if _, err := TextCompletion(api, httpClient, request); err == nil || err.Status() != http.StatusBadRequest {
+			t.Errorf("expected a bad request for %+v, got %v", request, err)
+		}
+	}
+	if _, err := StreamTextCompletion(api, httpClient, &TextCompletionRequest{Prompt: "Hi", BestOf: 3}); err == nil {
+		t.Error("expected an error for best_of with stream")
+	}
+	if _, err := ChatCompletion(api, httpClient, &CompletionRequest[DefaultMessages]{Model: "gpt-3.5-turbo-instruct", Messages: DefaultMessages{{Role: "user", Content: "Hi"}}}); err == nil {
+		t.Error("expected an error for a chat completion with a text completion model")
+	}
+	if len(httpClient.bodies) != 0 {
+		t.Errorf("expected invalid requests not to be sent, got %v", httpClient.bodies)
+	}
+}
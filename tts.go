@@ -1,66 +1,157 @@
 package openai
 
 import (
-	"encoding/json"
+	"fmt"
 	"io"
-	"net/http"
-
-	"github.com/Simplou/goxios"
 )
 
 const (
 	alloy   = "alloy"
+	ash     = "ash"
+	ballad  = "ballad"
+	cedar   = "cedar"
+	coral   = "coral"
 	echo    = "echo"
 	fable   = "fable"
+	marin   = "marin"
 	onyx    = "onyx"
 	nova    = "nova"
+	sage    = "sage"
 	shimmer = "shimmer"
+	verse   = "verse"
+)
+
+// Models of the speech API.
+const (
+	TTS1Model         = "tts-1"
+	TTS1HDModel       = "tts-1-hd"
+	GPT4oMiniTTSModel = "gpt-4o-mini-tts"
+)
+
+// Audio formats of the speech API.
+const (
+	SpeechFormatMP3  = "mp3"
+	SpeechFormatOpus = "opus"
+	SpeechFormatAAC  = "aac"
+	SpeechFormatFLAC = "flac"
+	SpeechFormatWAV  = "wav"
+	SpeechFormatPCM  = "pcm" // Raw 24kHz 16-bit signed little-endian mono samples, without header.
 )
 
+// speechContentTypes maps the speech formats to their content type.
+var speechContentTypes = map[string]string{
+	SpeechFormatMP3:  "audio/mpeg",
+	SpeechFormatOpus: "audio/ogg",
+	SpeechFormatAAC:  "audio/aac",
+	SpeechFormatFLAC: "audio/flac",
+	SpeechFormatWAV:  "audio/wav",
+	SpeechFormatPCM:  "audio/pcm",
+}
+
+// Speed limits of the speech API.
+const (
+	MinSpeechSpeed = 0.25
+	MaxSpeechSpeed = 4.0
+)
+
+// SpeechVoices holds the built-in voices. Voice accepts any string, so voices
+// released after this list can be used by name.
 var SpeechVoices = &openaiSpeechVoices{
 	Alloy:   alloy,
+	Ash:     ash,
+	Ballad:  ballad,
+	Cedar:   cedar,
+	Coral:   coral,
 	Echo:    echo,
 	Fable:   fable,
+	Marin:   marin,
 	Onyx:    onyx,
 	Nova:    nova,
+	Sage:    sage,
 	Shimmer: shimmer,
+	Verse:   verse,
 }
 
 type (
 	// openaiSpeechVoices holds the available voices for OpenAI speech synthesis.
 	openaiSpeechVoices struct {
 		Alloy   string
+		Ash     string
+		Ballad  string
+		Cedar   string
+		Coral   string
 		Echo    string
 		Fable   string
+		Marin   string
 		Onyx    string
 		Nova    string
+		Sage    string
 		Shimmer string
+		Verse   string
 	}
 
 	// SpeechRequestBody represents the request body for the speech API.
 	SpeechRequestBody struct {
-		Model string `json:"model"` // The model for speech synthesis.
-		Input string `json:"input"` // The input text for synthesis.
-		Voice string `json:"voice"` // The voice to be used for synthesis.
+		Model          string  `json:"model"`                     // The model for speech synthesis.
+		Input          string  `json:"input"`                     // The input text for synthesis.
+		Voice          string  `json:"voice"`                     // The voice to be used for synthesis.
+		ResponseFormat string  `json:"response_format,omitempty"` // One of the SpeechFormat constants, mp3 by default.
+		Speed          float64 `json:"speed,omitempty"`           // From 0.25 to 4.0, 1.0 by default.
+		// Instructions control the voice, such as its tone or accent. Not supported by tts-1 and tts-1-hd.
+		Instructions string `json:"instructions,omitempty"`
+	}
+
+	// SpeechAudio is the audio returned by the speech API. It must be closed.
+	SpeechAudio struct {
+		io.ReadCloser
+		ContentType string // The Content-Type of the response, such as audio/mpeg.
 	}
 )
 
-func TextToSpeech(api OpenAIClient, httpClient HTTPClient, body *SpeechRequestBody) (io.ReadCloser, *OpenAIErr) {
-	api.AddHeader(contentTypeJSON)
-	b, err := json.Marshal(body)
-	if err != nil {
-		return nil, NewOpenAIErr(err, 500, "marshal_json_error")
+// Custom returns the name of a voice that is not listed in SpeechVoices.
+func (v *openaiSpeechVoices) Custom(name string) string {
+	return name
+}
+
+// All returns the built-in voices.
+func (v *openaiSpeechVoices) All() []string {
+	return []string{v.Alloy, v.Ash, v.Ballad, v.Cedar, v.Coral, v.Echo, v.Fable, v.Marin, v.Onyx, v.Nova, v.Sage, v.Shimmer, v.Verse}
+}
+
+func (body *SpeechRequestBody) validate() error {
+	if body.ResponseFormat != "" {
+		if _, ok := speechContentTypes[body.ResponseFormat]; !ok {
+			return fmt.Errorf("unsupported response_format %q", body.ResponseFormat)
+		}
 	}
-	options := goxios.RequestOpts{
-		Headers: api.Headers(),
-		Body:    ioReader(b),
+	if body.Speed != 0 && (body.Speed < MinSpeechSpeed || body.Speed > MaxSpeechSpeed) {
+		return fmt.Errorf("speed must be between %v and %v, got %v", MinSpeechSpeed, MaxSpeechSpeed, body.Speed)
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/audio/speech", &options)
-	if res.StatusCode != http.StatusOK {
-		return nil, openaiHttpError(res)
+	if body.Instructions != "" && (body.Model == TTS1Model || body.Model == TTS1HDModel) {
+		return fmt.Errorf("%s does not support instructions", body.Model)
 	}
+	return nil
+}
+
+// contentType returns the content type of the requested format.
+func (body *SpeechRequestBody) contentType() string {
+	if body.ResponseFormat == "" {
+		return speechContentTypes[SpeechFormatMP3]
+	}
+	return speechContentTypes[body.ResponseFormat]
+}
+
+func TextToSpeech(api OpenAIClient, httpClient HTTPClient, body *SpeechRequestBody) (*SpeechAudio, *OpenAIErr) {
+	if err := body.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	res, err := postJSON(api, httpClient, "/audio/speech", body)
 	if err != nil {
-		return nil, closeBody(res.Body, err)
+		return nil, err
+	}
+	contentType := res.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = body.contentType()
 	}
-	return res.Body, nil
+	return &SpeechAudio{ReadCloser: res.Body, ContentType: contentType}, nil
 }
@@ -0,0 +1,290 @@
+package openai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxSpeechInputLength is the longest input, in characters, accepted by the speech API.
+const MaxSpeechInputLength = 4096
+
+// DefaultLongSpeechConcurrency is the number of chunks synthesized at the same time
+// when LongSpeechOpts.Concurrency is not set.
+const DefaultLongSpeechConcurrency = 4
+
+// LongSpeechOpts configures LongTextToSpeech.
+type LongSpeechOpts struct {
+	// MaxChunkLength is the longest chunk of text, in characters, sent in one request.
+	MaxChunkLength int
+	// Concurrency bounds the chunks being synthesized or waiting to be read. It
+	// requires NewHTTPClient: chunks sent with a single HTTPClient are synthesized
+	// one at a time.
+	Concurrency   int
+	NewHTTPClient HTTPClientFactory
+}
+
+// SplitTextAtSentences splits text into chunks of at most limit characters,
+// breaking between sentences when possible, then between words. A limit of 0
+// or less uses MaxSpeechInputLength.
+func SplitTextAtSentences(text string, limit int) []string {
+	if limit <= 0 {
+		limit = MaxSpeechInputLength
+	}
+	var chunks []string
+	var chunk strings.Builder
+	flush := func() {
+		if t := strings.TrimSpace(chunk.String()); t != "" {
+			chunks = append(chunks, t)
+		}
+		chunk.Reset()
+	}
+	for _, sentence := range sentences(text) {
+		if utf8.RuneCountInString(strings.TrimSpace(chunk.String()+sentence)) <= limit {
+			chunk.WriteString(sentence)
+			continue
+		}
+		flush()
+		if utf8.RuneCountInString(strings.TrimSpace(sentence)) <= limit {
+			chunk.WriteString(sentence)
+			continue
+		}
+		for _, word := range strings.Fields(sentence) {
+			for utf8.RuneCountInString(word) > limit {
+				flush()
+				runes := []rune(word)
+				chunks = append(chunks, string(runes[:limit]))
+				word = string(runes[limit:])
+			}
+			if utf8.RuneCountInString(chunk.String()+word) > limit {
+				flush()
+			}
+			chunk.WriteString(word + " ")
+		}
+	}
+	flush()
+	return chunks
+}
+
+// sentences splits text after sentence-ending punctuation followed by a space,
+// keeping the spaces with the sentence they follow.
+func sentences(text string) []string {
+	var result []string
+	runes := []rune(text)
+	start, offset := 0, 0
+	for i, r := range runes {
+		offset += utf8.RuneLen(r)
+		if !strings.ContainsRune(".!?…。！？", r) && r != '\n' {
+			continue
+		}
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		end := offset
+		for j := i + 1; j < len(runes) && unicode.IsSpace(runes[j]); j++ {
+			end += utf8.RuneLen(runes[j])
+		}
+		result = append(result, text[start:end])
+		start = end
+	}
+	if start < len(text) {
+		result = append(result, text[start:])
+	}
+	return result
+}
+
+// LongTextToSpeech synthesizes text of any length. The input is split at sentence
+// boundaries into chunks under the API limit, which are synthesized concurrently
+// and stitched in order into one stream. Reading starts as soon as the first chunk
+// starts arriving. MP3, Opus, AAC and PCM chunks are concatenated directly. FLAC
+// output cannot be concatenated and is not supported.
+//
+// WAV chunks are written under a single header. As the total size is unknown
+// until the last chunk, the header holds placeholder sizes (0xFFFFFFFF), as in
+// the WAV files streamed by the API: players reading the stream accept them,
+// but a saved file only has a valid header once FinalizeWAV rewrote it:
+//
+//	f, _ := os.Create("speech.wav")
+//	io.Copy(f, audio)
+//	err := FinalizeWAV(f)
+func LongTextToSpeech(api OpenAIClient, httpClient HTTPClient, body *SpeechRequestBody, opts *LongSpeechOpts) (*SpeechAudio, *OpenAIErr) {
+	if opts == nil {
+		opts = &LongSpeechOpts{}
+	}
+	if err := body.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	if body.ResponseFormat == SpeechFormatFLAC {
+		return nil, errInvalidRequest(fmt.Errorf("%s output cannot be concatenated", SpeechFormatFLAC))
+	}
+	limit := opts.MaxChunkLength
+	if limit <= 0 || limit > MaxSpeechInputLength {
+		limit = MaxSpeechInputLength
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultLongSpeechConcurrency
+	}
+	concurrency = opts.NewHTTPClient.concurrency(concurrency)
+	chunks := SplitTextAtSentences(body.Input, limit)
+	if len(chunks) == 0 {
+		return nil, errInvalidRequest(fmt.Errorf("input is empty"))
+	}
+
+	pr, pw := io.Pipe()
+	speech := &longSpeech{PipeReader: pr, done: make(chan struct{})}
+	buffers := make([]*chunkBuffer, len(chunks))
+	for i := range buffers {
+		buffers[i] = newChunkBuffer()
+	}
+	slots := make(chan struct{}, concurrency)
+	go func() {
+		for i, chunk := range chunks {
+			select {
+			case slots <- struct{}{}:
+			case <-speech.done:
+				// Release the writer, which may be waiting for chunk i.
+				for _, buffer := range buffers[i:] {
+					buffer.CloseWithError(io.ErrClosedPipe)
+				}
+				return
+			}
+			go func(i int, chunk string) {
+				chunkBody := *body
+				chunkBody.Input = chunk
+				audio, err := TextToSpeech(api, opts.NewHTTPClient.client(httpClient), &chunkBody)
+				if err != nil {
+					buffers[i].CloseWithError(err)
+					return
+				}
+				_, copyErr := io.Copy(buffers[i], audio)
+				audio.Close()
+				buffers[i].CloseWithError(copyErr)
+			}(i, chunk)
+		}
+	}()
+	go func() {
+		defer speech.stop()
+		for i, buffer := range buffers {
+			if err := writeSpeechChunk(pw, buffer, body.ResponseFormat, i == 0); err != nil {
+				pw.CloseWithError(asOpenAIErr(err, errCannotReadBody))
+				return
+			}
+			<-slots
+		}
+		pw.Close()
+	}()
+	return &SpeechAudio{ReadCloser: speech, ContentType: body.contentType()}, nil
+}
+
+// writeSpeechChunk copies one synthesized chunk to w, dropping the headers that
+// must not be repeated in the middle of the stream.
+func writeSpeechChunk(w io.Writer, chunk io.Reader, format string, first bool) error {
+	switch format {
+	case SpeechFormatWAV:
+		pcmFormat, _, err := readWAVHeader(chunk)
+		if err != nil {
+			return err
+		}
+		if first {
+			if err := writeWAVHeader(w, pcmFormat, wavUnknownSize); err != nil {
+				return err
+			}
+		}
+	case "", SpeechFormatMP3:
+		if !first {
+			r := bufio.NewReader(chunk)
+			if err := skipID3(r); err != nil {
+				return err
+			}
+			chunk = r
+		}
+	}
+	_, err := io.Copy(w, chunk)
+	return err
+}
+
+// skipID3 discards the ID3v2 tag at the start of an MP3 stream, if any.
+func skipID3(r *bufio.Reader) error {
+	header, err := r.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	size := int(header[6]&0x7f)<<21 | int(header[7]&0x7f)<<14 | int(header[8]&0x7f)<<7 | int(header[9]&0x7f)
+	if header[5]&0x10 != 0 {
+		size += 10 // Footer.
+	}
+	_, err = r.Discard(10 + size)
+	return err
+}
+
+// longSpeech is the stream returned by LongTextToSpeech. Closing it stops
+// synthesizing the chunks that have not started yet.
+type longSpeech struct {
+	*io.PipeReader
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *longSpeech) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *longSpeech) Close() error {
+	s.stop()
+	return s.PipeReader.Close()
+}
+
+// chunkBuffer is an in-memory pipe without a size limit: a chunk is downloaded
+// as fast as the API sends it while the previous chunks are still being read.
+type chunkBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	err    error
+	closed bool
+}
+
+func newChunkBuffer() *chunkBuffer {
+	b := &chunkBuffer{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *chunkBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	b.cond.Broadcast()
+	return len(p), nil
+}
+
+// CloseWithError ends the chunk. Reads return err, or io.EOF when err is nil,
+// once the buffered data has been read.
+func (b *chunkBuffer) CloseWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err, b.closed = err, true
+	b.cond.Broadcast()
+}
+
+func (b *chunkBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.buf) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if len(b.buf) > 0 {
+		n := copy(p, b.buf)
+		b.buf = b.buf[n:]
+		return n, nil
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return 0, io.EOF
+}
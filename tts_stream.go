@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// speechStreamBufferSize is the size of the writes made while streaming speech.
+// Small writes let players start before the whole audio has been received.
+const speechStreamBufferSize = 16 << 10
+
+// SpeechStreamOpts configures StreamSpeech.
+type SpeechStreamOpts struct {
+	// PCMAsWAV wraps pcm output in a WAV header, so browsers can play it while it
+	// is still downloading.
+	PCMAsWAV bool
+}
+
+// Stream copies the audio to w as it arrives and closes it. Reads follow the pace
+// of w, so a slow client slows down the download instead of filling memory.
+// Writers that implement http.Flusher, such as http.ResponseWriter, are flushed
+// after every write. Canceling ctx stops the copy and returns ctx.Err().
+func (a *SpeechAudio) Stream(ctx context.Context, w io.Writer) (int64, error) {
+	defer a.Close()
+	stop := context.AfterFunc(ctx, func() { a.Close() })
+	defer stop()
+	if rw, ok := w.(http.ResponseWriter); ok && rw.Header().Get("Content-Type") == "" {
+		rw.Header().Set("Content-Type", a.ContentType)
+	}
+	n, err := io.CopyBuffer(flushWriter{w}, a, make([]byte, speechStreamBufferSize))
+	if ctx.Err() != nil {
+		return n, ctx.Err()
+	}
+	return n, err
+}
+
+// StreamSpeech synthesizes body and streams the audio to w without buffering
+// the whole file. See SpeechAudio.Stream.
+func StreamSpeech(ctx context.Context, api OpenAIClient, httpClient HTTPClient, body *SpeechRequestBody, w io.Writer, opts *SpeechStreamOpts) (int64, *OpenAIErr) {
+	if opts == nil {
+		opts = &SpeechStreamOpts{}
+	}
+	audio, openaiErr := TextToSpeech(api, httpClient, body)
+	if openaiErr != nil {
+		return 0, openaiErr
+	}
+	var encoder *WAVEncoder
+	if opts.PCMAsWAV && body.ResponseFormat == SpeechFormatPCM {
+		encoder = NewWAVEncoder(w, PCM16Mono24kHz)
+		audio.ContentType = speechContentTypes[SpeechFormatWAV]
+		if rw, ok := w.(http.ResponseWriter); ok && rw.Header().Get("Content-Type") == "" {
+			rw.Header().Set("Content-Type", audio.ContentType)
+		}
+		w = encoder
+	}
+	n, err := audio.Stream(ctx, w)
+	if err != nil {
+		return n, asOpenAIErr(err, errCannotReadBody)
+	}
+	if encoder != nil {
+		if err := encoder.Close(); err != nil {
+			return n, errCannotReadBody(err)
+		}
+	}
+	return n, nil
+}
+
+// flushWriter flushes the underlying writer after every write when it supports it.
+type flushWriter struct {
+	w io.Writer
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// WAVEncoder writes PCM samples as a WAV file. The header is written before the
+// first samples with unknown sizes, so the file can be played while it is written.
+type WAVEncoder struct {
+	w             io.Writer
+	format        PCMFormat
+	headerWritten bool
+}
+
+// NewWAVEncoder returns an encoder that writes samples of the given format to w.
+func NewWAVEncoder(w io.Writer, format PCMFormat) *WAVEncoder {
+	return &WAVEncoder{w: w, format: format}
+}
+
+func (e *WAVEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return writeWAVHeader(e.w, e.format, wavUnknownSize)
+}
+
+func (e *WAVEncoder) Write(p []byte) (int, error) {
+	if err := e.writeHeader(); err != nil {
+		return 0, err
+	}
+	return e.w.Write(p)
+}
+
+// Flush flushes the underlying writer when it implements http.Flusher.
+func (e *WAVEncoder) Flush() {
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close ends the file. When the underlying writer is an io.WriteSeeker, such as
+// an *os.File, the header sizes are fixed with FinalizeWAV. The underlying writer
+// is not closed.
+func (e *WAVEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	if seeker, ok := e.w.(io.WriteSeeker); ok {
+		return FinalizeWAV(seeker)
+	}
+	return nil
+}
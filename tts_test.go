@@ -2,11 +2,17 @@ package openai
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Simplou/goxios"
 )
@@ -106,3 +112,308 @@ func TestTextToSpeech(t *testing.T) {
 		})
 	}
 }
+
+func TestTextToSpeechOptions(t *testing.T) {
+	client := &OpenAIClientMock{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	testCases := []struct {
+		name                string
+		body                *SpeechRequestBody
+		expectedContentType string
+		expectedErr         bool
+	}{
+		{
+			name:                "Default format",
+			body:                &SpeechRequestBody{Model: TTS1Model, Input: "Hello", Voice: SpeechVoices.Coral},
+			expectedContentType: "audio/mpeg",
+		},
+		{
+			name:                "Wav with speed and instructions",
+			body:                &SpeechRequestBody{Model: GPT4oMiniTTSModel, Input: "Hello", Voice: SpeechVoices.Custom("new-voice"), ResponseFormat: SpeechFormatWAV, Speed: 1.5, Instructions: "Speak cheerfully."},
+			expectedContentType: "audio/wav",
+		},
+		{
+			name:        "Speed too low",
+			body:        &SpeechRequestBody{Model: TTS1Model, Input: "Hello", Voice: SpeechVoices.Alloy, Speed: 0.1},
+			expectedErr: true,
+		},
+		{
+			name:        "Unsupported format",
+			body:        &SpeechRequestBody{Model: TTS1Model, Input: "Hello", Voice: SpeechVoices.Alloy, ResponseFormat: "ogg"},
+			expectedErr: true,
+		},
+		{
+			name:        "Instructions with tts-1",
+			body:        &SpeechRequestBody{Model: TTS1Model, Input: "Hello", Voice: SpeechVoices.Alloy, Instructions: "Whisper."},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			audio, err := TextToSpeech(client, new(MockHTTPClient), tc.body)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			defer audio.Close()
+			if audio.ContentType != tc.expectedContentType {
+				t.Errorf("Expected content type %s, got %s", tc.expectedContentType, audio.ContentType)
+			}
+		})
+	}
+}
+
+// MockSpeechHTTPClient synthesizes the input text as its own bytes, in the requested format.
+type MockSpeechHTTPClient struct {
+	failOn string
+	// delay holds back the end of every audio body.
+	delay time.Duration
+}
+
+// delayedEOF sleeps before ending a body, as a connection still downloading.
+type delayedEOF time.Duration
+
+func (d delayedEOF) Read(p []byte) (int, error) {
+	time.Sleep(time.Duration(d))
+	return 0, io.EOF
+}
+
+func (c *MockSpeechHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockSpeechHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	body := new(SpeechRequestBody)
+	if err := goxios.DecodeJSON(opts.Body, body); err != nil {
+		return nil, err
+	}
+	if body.Input == c.failOn {
+		return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	audio := &bytes.Buffer{}
+	switch body.ResponseFormat {
+	case SpeechFormatWAV:
+		writeWAVHeader(audio, PCM16Mono24kHz, wavUnknownSize)
+	case SpeechFormatMP3:
+		audio.Write([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 2, 'T', 'G'})
+	}
+	audio.WriteString(body.Input)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(io.MultiReader(audio, delayedEOF(c.delay)))}, nil
+}
+
+func TestSplitTextAtSentences(t *testing.T) {
+	text := "First sentence. Second one! Third? A very long sentence without any break"
+	chunks := SplitTextAtSentences(text, 30)
+	expected := []string{"First sentence. Second one!", "Third?", "A very long sentence without", "any break"}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %q, got %q", expected, chunks)
+	}
+	for i := range chunks {
+		if chunks[i] != expected[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, expected[i], chunks[i])
+		}
+	}
+}
+
+func TestSplitTextAtSentencesDefaultLimit(t *testing.T) {
+	text := strings.Repeat("word ", 2000)
+	for _, limit := range []int{0, -1} {
+		chunks := SplitTextAtSentences(text, limit)
+		if len(chunks) != 3 {
+			t.Errorf("Limit %d: expected 3 chunks, got %d", limit, len(chunks))
+		}
+		for _, chunk := range chunks {
+			if len(chunk) > MaxSpeechInputLength {
+				t.Errorf("Limit %d: chunk of %d characters", limit, len(chunk))
+			}
+		}
+	}
+}
+
+func TestLongTextToSpeechSharedClient(t *testing.T) {
+	server := newConcurrencyServer(func(w http.ResponseWriter, r *http.Request) {
+		var body SpeechRequestBody
+		goxios.DecodeJSON(r.Body, &body)
+		w.Header().Set("Content-Type", "audio/pcm")
+		io.WriteString(w, body.Input)
+	})
+	defer server.Close()
+	client := MockClient{server.URL}
+	body := &SpeechRequestBody{Model: TTS1Model, Input: "One. Two. Three. Four. Five. Six.", Voice: SpeechVoices.Alloy, ResponseFormat: SpeechFormatPCM}
+	read := func(httpClient HTTPClient, opts *LongSpeechOpts) string {
+		audio, err := LongTextToSpeech(client, httpClient, body, opts)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		defer audio.Close()
+		b, readErr := io.ReadAll(audio)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		return string(b)
+	}
+	if b := read(goxios.New(context.Background()), &LongSpeechOpts{MaxChunkLength: 6}); b != "One.Two.Three.Four.Five.Six." {
+		t.Errorf("Unexpected audio %q", b)
+	}
+	if n := server.maxInFlight.Load(); n != 1 {
+		t.Errorf("Expected the shared client to synthesize one chunk at a time, got %d", n)
+	}
+
+	server.maxInFlight.Store(0)
+	opts := &LongSpeechOpts{MaxChunkLength: 6, Concurrency: 3, NewHTTPClient: func() HTTPClient { return goxios.New(context.Background()) }}
+	if b := read(nil, opts); b != "One.Two.Three.Four.Five.Six." {
+		t.Errorf("Unexpected audio %q", b)
+	}
+	if n := server.maxInFlight.Load(); n > 3 {
+		t.Errorf("Expected at most 3 parallel chunks, got %d", n)
+	}
+}
+
+func TestLongTextToSpeechClose(t *testing.T) {
+	client := &OpenAIClientMock{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	audio, err := LongTextToSpeech(client, &MockSpeechHTTPClient{delay: 20 * time.Millisecond}, &SpeechRequestBody{
+		Model:          TTS1Model,
+		Input:          "One. Two. Three.",
+		Voice:          SpeechVoices.Alloy,
+		ResponseFormat: SpeechFormatPCM,
+	}, &LongSpeechOpts{MaxChunkLength: 6})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	b := make([]byte, len("One."))
+	if _, err := io.ReadFull(audio, b); err != nil || string(b) != "One." {
+		t.Fatalf("Unexpected first chunk %q: %v", b, err)
+	}
+	// The first chunk is still downloading: the next ones are never synthesized.
+	audio.Close()
+	running := func() bool {
+		stacks := make([]byte, 1<<20)
+		return bytes.Contains(stacks[:runtime.Stack(stacks, true)], []byte("openai.LongTextToSpeech.func"))
+	}
+	for deadline := time.Now().Add(time.Second); running() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running() {
+		t.Error("Expected the goroutines to stop after closing the audio")
+	}
+}
+
+func TestLongTextToSpeech(t *testing.T) {
+	client := &OpenAIClientMock{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	input := "One. Two. Three. Four. Five. Six."
+	expectedAudio := "One.Two.Three.Four.Five.Six."
+	testCases := []struct {
+		name     string
+		format   string
+		expected []byte
+	}{
+		{name: "Mp3 without repeated tags", format: SpeechFormatMP3, expected: append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 2, 'T', 'G'}, expectedAudio...)},
+		{name: "Pcm", format: SpeechFormatPCM, expected: []byte(expectedAudio)},
+		{name: "Wav with one header", format: SpeechFormatWAV, expected: func() []byte {
+			b := &bytes.Buffer{}
+			writeWAVHeader(b, PCM16Mono24kHz, wavUnknownSize)
+			b.WriteString(expectedAudio)
+			return b.Bytes()
+		}()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			audio, err := LongTextToSpeech(client, new(MockSpeechHTTPClient), &SpeechRequestBody{
+				Model:          TTS1Model,
+				Input:          input,
+				Voice:          SpeechVoices.Alloy,
+				ResponseFormat: tc.format,
+			}, &LongSpeechOpts{MaxChunkLength: 6, Concurrency: 2})
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			defer audio.Close()
+			b, readErr := io.ReadAll(audio)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if !bytes.Equal(b, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, b)
+			}
+		})
+	}
+
+	audio, err := LongTextToSpeech(client, &MockSpeechHTTPClient{failOn: "Four."}, &SpeechRequestBody{
+		Model: TTS1Model,
+		Input: input,
+		Voice: SpeechVoices.Alloy,
+	}, &LongSpeechOpts{MaxChunkLength: 6})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer audio.Close()
+	b, readErr := io.ReadAll(audio)
+	if readErr == nil {
+		t.Error("Expected the failed chunk to fail the stream")
+	}
+	if string(b) != "One.Two.Three." {
+		t.Errorf("Expected the chunks before the failure, got %q", b)
+	}
+}
+
+func TestStreamSpeech(t *testing.T) {
+	client := &OpenAIClientMock{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	body := &SpeechRequestBody{Model: TTS1Model, Input: "pcm samples", Voice: SpeechVoices.Alloy, ResponseFormat: SpeechFormatPCM}
+
+	recorder := httptest.NewRecorder()
+	n, err := StreamSpeech(context.Background(), client, new(MockSpeechHTTPClient), body, recorder, &SpeechStreamOpts{PCMAsWAV: true})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if n != int64(len(body.Input)) {
+		t.Errorf("Expected %d bytes of samples, got %d", len(body.Input), n)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "audio/wav" {
+		t.Errorf("Expected audio/wav, got %s", contentType)
+	}
+	if !recorder.Flushed {
+		t.Error("Expected the response to be flushed while streaming")
+	}
+	format, size, readErr := readWAVHeader(recorder.Body)
+	if readErr != nil || format != PCM16Mono24kHz || size != wavUnknownSize {
+		t.Fatalf("Unexpected header %+v %d: %v", format, size, readErr)
+	}
+	if recorder.Body.String() != body.Input {
+		t.Errorf("Expected samples %q, got %q", body.Input, recorder.Body.String())
+	}
+
+	file, fileErr := os.Create(filepath.Join(t.TempDir(), "speech.wav"))
+	if fileErr != nil {
+		t.Fatal(fileErr)
+	}
+	defer file.Close()
+	if _, err := StreamSpeech(context.Background(), client, new(MockSpeechHTTPClient), body, file, &SpeechStreamOpts{PCMAsWAV: true}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	file.Seek(0, io.SeekStart)
+	_, size, readErr = readWAVHeader(file)
+	if readErr != nil || size != uint32(len(body.Input)) {
+		t.Errorf("Expected a finalized header with size %d, got %d: %v", len(body.Input), size, readErr)
+	}
+}
+
+func TestSpeechAudioStreamCancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	audio := &SpeechAudio{ReadCloser: pr, ContentType: "audio/mpeg"}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := audio.Stream(ctx, io.Discard)
+		done <- err
+	}()
+	pw.Write([]byte("first frames"))
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
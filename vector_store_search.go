@@ -0,0 +1,356 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Types of the attribute filters.
+const (
+	FilterTypeEq  = "eq"
+	FilterTypeNe  = "ne"
+	FilterTypeGt  = "gt"
+	FilterTypeGte = "gte"
+	FilterTypeLt  = "lt"
+	FilterTypeLte = "lte"
+	FilterTypeAnd = "and"
+	FilterTypeOr  = "or"
+)
+
+// AttributeFilter selects files by their attributes: a comparison of the
+// attribute Key with Value, or the and/or of Filters.
+type AttributeFilter struct {
+	Type    string
+	Key     string
+	Value   any
+	Filters []*AttributeFilter
+}
+
+func FilterEq(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeEq, Key: key, Value: value}
+}
+
+func FilterNe(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeNe, Key: key, Value: value}
+}
+
+func FilterGt(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeGt, Key: key, Value: value}
+}
+
+func FilterGte(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeGte, Key: key, Value: value}
+}
+
+func FilterLt(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeLt, Key: key, Value: value}
+}
+
+func FilterLte(key string, value any) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeLte, Key: key, Value: value}
+}
+
+func FilterAnd(filters ...*AttributeFilter) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeAnd, Filters: filters}
+}
+
+func FilterOr(filters ...*AttributeFilter) *AttributeFilter {
+	return &AttributeFilter{Type: FilterTypeOr, Filters: filters}
+}
+
+func (f *AttributeFilter) compound() bool {
+	return f.Type == FilterTypeAnd || f.Type == FilterTypeOr
+}
+
+func (f AttributeFilter) MarshalJSON() ([]byte, error) {
+	if f.compound() {
+		return json.Marshal(struct {
+			Type    string             `json:"type"`
+			Filters []*AttributeFilter `json:"filters"`
+		}{f.Type, f.Filters})
+	}
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Key   string `json:"key"`
+		Value any    `json:"value"`
+	}{f.Type, f.Key, f.Value})
+}
+
+func (f *AttributeFilter) UnmarshalJSON(b []byte) error {
+	var filter struct {
+		Type    string             `json:"type"`
+		Key     string             `json:"key"`
+		Value   any                `json:"value"`
+		Filters []*AttributeFilter `json:"filters"`
+	}
+	if err := json.Unmarshal(b, &filter); err != nil {
+		return err
+	}
+	*f = AttributeFilter(filter)
+	return nil
+}
+
+func (f *AttributeFilter) validate() error {
+	switch f.Type {
+	case FilterTypeAnd, FilterTypeOr:
+		if len(f.Filters) == 0 {
+			return fmt.Errorf("%s filter without filters", f.Type)
+		}
+		for _, filter := range f.Filters {
+			if err := filter.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterTypeEq, FilterTypeNe, FilterTypeGt, FilterTypeGte, FilterTypeLt, FilterTypeLte:
+	default:
+		return fmt.Errorf("invalid filter type %q", f.Type)
+	}
+	if f.Key == "" {
+		return fmt.Errorf("%s filter without key", f.Type)
+	}
+	if _, ok := attributeNumber(f.Value); ok {
+		return nil
+	}
+	switch f.Value.(type) {
+	case string, bool:
+		return nil
+	}
+	return fmt.Errorf("filter on %s: value must be a string, a number or a boolean, got %T", f.Key, f.Value)
+}
+
+// Match reports whether attributes pass the filter, as the API evaluates it,
+// to filter local documents the same way. Comparing values of different types
+// never matches, and only numbers are ordered.
+func (f *AttributeFilter) Match(attributes map[string]any) bool {
+	switch f.Type {
+	case FilterTypeAnd:
+		for _, filter := range f.Filters {
+			if !filter.Match(attributes) {
+				return false
+			}
+		}
+		return true
+	case FilterTypeOr:
+		for _, filter := range f.Filters {
+			if filter.Match(attributes) {
+				return true
+			}
+		}
+		return false
+	}
+	value, ok := attributes[f.Key]
+	if !ok {
+		return false
+	}
+	switch f.Type {
+	case FilterTypeEq:
+		return attributeEqual(value, f.Value)
+	case FilterTypeNe:
+		return !attributeEqual(value, f.Value)
+	}
+	a, aNumber := attributeNumber(value)
+	b, bNumber := attributeNumber(f.Value)
+	if !aNumber || !bNumber {
+		return false
+	}
+	switch f.Type {
+	case FilterTypeGt:
+		return a > b
+	case FilterTypeGte:
+		return a >= b
+	case FilterTypeLt:
+		return a < b
+	case FilterTypeLte:
+		return a <= b
+	}
+	return false
+}
+
+func attributeEqual(a, b any) bool {
+	if x, ok := attributeNumber(a); ok {
+		y, ok := attributeNumber(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return false
+}
+
+// attributeNumber converts the numbers of any Go type, and the float64 of
+// decoded JSON, to float64.
+func attributeNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+type (
+	VectorStoreSearchRequest struct {
+		Query   string           `json:"query"`
+		Filters *AttributeFilter `json:"filters,omitempty"`
+		// MaxNumResults is between 1 and 50, 10 by default.
+		MaxNumResults  int             `json:"max_num_results,omitempty"`
+		RankingOptions *RankingOptions `json:"ranking_options,omitempty"`
+		// RewriteQuery lets the API rewrite the query for search.
+		RewriteQuery bool `json:"rewrite_query,omitempty"`
+	}
+
+	// VectorStoreSearchResult is a chunk of a file matching a search.
+	VectorStoreSearchResult struct {
+		FileId     string         `json:"file_id"`
+		Filename   string         `json:"filename"`
+		Score      float64        `json:"score"`
+		Attributes map[string]any `json:"attributes,omitempty"`
+		Content    []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+
+	VectorStoreSearchResults struct {
+		Object string `json:"object"`
+		// SearchQuery is the query searched, after rewriting.
+		SearchQuery SearchQuery               `json:"search_query"`
+		Data        []VectorStoreSearchResult `json:"data"`
+		HasMore     bool                      `json:"has_more"`
+		NextPage    string                    `json:"next_page,omitempty"`
+	}
+
+	// SearchQuery is the list of queries searched. The API returns a string for
+	// a single query.
+	SearchQuery []string
+)
+
+func (q *SearchQuery) UnmarshalJSON(b []byte) error {
+	var query string
+	if json.Unmarshal(b, &query) == nil {
+		*q = SearchQuery{query}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(q))
+}
+
+// Text returns the text of the chunk.
+func (r *VectorStoreSearchResult) Text() string {
+	texts := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		texts = append(texts, content.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Hits returns the results as search hits, best first, to compare them with
+// the hits of LocalSearch.
+func (r *VectorStoreSearchResults) Hits() []SearchHit {
+	hits := make([]SearchHit, len(r.Data))
+	for i, result := range r.Data {
+		hits[i] = SearchHit{Id: result.FileId, Text: result.Text(), Score: result.Score, Attributes: result.Attributes}
+	}
+	return hits
+}
+
+// SearchVectorStore searches the chunks of a vector store most relevant to a
+// query, optionally among the files whose attributes match body.Filters.
+func SearchVectorStore(api OpenAIClient, httpClient HTTPClient, vectorStoreId string, body *VectorStoreSearchRequest) (*VectorStoreSearchResults, *OpenAIErr) {
+	if vectorStoreId == "" || body.Query == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and query are required"))
+	}
+	if body.Filters != nil {
+		if err := body.Filters.validate(); err != nil {
+			return nil, errInvalidRequest(err)
+		}
+	}
+	if body.MaxNumResults < 0 || body.MaxNumResults > 50 {
+		return nil, errInvalidRequest(fmt.Errorf("max_num_results must be between 1 and 50, got %d", body.MaxNumResults))
+	}
+	return postVectorStores[VectorStoreSearchResults](api, httpClient, pathID(vectorStoreId)+"/search", body)
+}
+
+type (
+	// SearchHit is a search result ranked by Score, from a vector store or from
+	// LocalSearch. Id identifies the document: the file id for vector stores.
+	SearchHit struct {
+		Id         string
+		Text       string
+		Score      float64
+		Attributes map[string]any
+	}
+
+	// SearchDocument is a document searched by LocalSearch, with the embedding
+	// of its text.
+	SearchDocument struct {
+		Id         string
+		Text       string
+		Embedding  []float64
+		Attributes map[string]any
+	}
+)
+
+// LocalSearch ranks documents by the cosine similarity of their embedding with
+// query, among the documents matching filter when it is not nil, and returns
+// the limit best, or all of them when limit is 0.
+func LocalSearch(query []float64, documents []SearchDocument, filter *AttributeFilter, limit int) []SearchHit {
+	queryNorm := norm(query)
+	var hits []SearchHit
+	for _, document := range documents {
+		if filter != nil && !filter.Match(document.Attributes) {
+			continue
+		}
+		score := 0.0
+		if n := queryNorm * norm(document.Embedding); n > 0 && len(document.Embedding) == len(query) {
+			score = dotProduct(query, document.Embedding) / n
+		}
+		hits = append(hits, SearchHit{Id: document.Id, Text: document.Text, Score: score, Attributes: document.Attributes})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// SearchRecall returns the share of the distinct ids of expected found in got,
+// such as the hits of a vector store against those of a local search. It is 1
+// when expected is empty.
+func SearchRecall(expected, got []SearchHit) float64 {
+	ids := map[string]bool{}
+	for _, hit := range expected {
+		ids[hit.Id] = false
+	}
+	if len(ids) == 0 {
+		return 1
+	}
+	found := 0
+	for _, hit := range got {
+		if seen, ok := ids[hit.Id]; ok && !seen {
+			ids[hit.Id] = true
+			found++
+		}
+	}
+	return float64(found) / float64(len(ids))
+}
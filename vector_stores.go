@@ -0,0 +1,347 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// Statuses of vector stores, and of the files and file batches they ingest.
+const (
+	VectorStoreStatusExpired    = "expired"
+	VectorStoreStatusInProgress = "in_progress"
+	VectorStoreStatusCompleted  = "completed"
+	VectorStoreStatusCancelled  = "cancelled"
+	VectorStoreStatusFailed     = "failed"
+)
+
+// Limits of the static chunking strategy, in tokens.
+const (
+	MinChunkSizeTokens = 100
+	MaxChunkSizeTokens = 4096
+)
+
+type (
+	// ChunkingStrategy splits the files added to a vector store. Use
+	// AutoChunkingStrategy or StaticChunkingStrategy.
+	ChunkingStrategy struct {
+		Type   string          `json:"type"`
+		Static *StaticChunking `json:"static,omitempty"`
+	}
+
+	StaticChunking struct {
+		MaxChunkSizeTokens int `json:"max_chunk_size_tokens"`
+		// ChunkOverlapTokens is at most half of MaxChunkSizeTokens.
+		ChunkOverlapTokens int `json:"chunk_overlap_tokens"`
+	}
+
+	// VectorStoreExpiration expires a vector store Days after its anchor,
+	// last_active_at.
+	VectorStoreExpiration struct {
+		Anchor string `json:"anchor"`
+		Days   int    `json:"days"`
+	}
+
+	CreateVectorStoreRequest struct {
+		Name             string                 `json:"name,omitempty"`
+		FileIds          []string               `json:"file_ids,omitempty"`
+		ExpiresAfter     *VectorStoreExpiration `json:"expires_after,omitempty"`
+		ChunkingStrategy *ChunkingStrategy      `json:"chunking_strategy,omitempty"`
+		Metadata         map[string]string      `json:"metadata,omitempty"`
+	}
+
+	// ModifyVectorStoreRequest updates a vector store. The fields left empty are
+	// not modified.
+	ModifyVectorStoreRequest struct {
+		Name         string                 `json:"name,omitempty"`
+		ExpiresAfter *VectorStoreExpiration `json:"expires_after,omitempty"`
+		Metadata     map[string]string      `json:"metadata,omitempty"`
+	}
+
+	// VectorStore is a store of file chunks searchable by the file search tool
+	// and SearchVectorStore. Times are Unix timestamps in seconds.
+	VectorStore struct {
+		Id           string                 `json:"id"`
+		Object       string                 `json:"object"`
+		CreatedAt    int64                  `json:"created_at"`
+		Name         string                 `json:"name"`
+		UsageBytes   int64                  `json:"usage_bytes"`
+		FileCounts   VectorStoreFileCounts  `json:"file_counts"`
+		Status       string                 `json:"status"`
+		ExpiresAfter *VectorStoreExpiration `json:"expires_after,omitempty"`
+		ExpiresAt    int64                  `json:"expires_at,omitempty"`
+		LastActiveAt int64                  `json:"last_active_at,omitempty"`
+		Metadata     map[string]string      `json:"metadata,omitempty"`
+	}
+
+	VectorStoreFileCounts struct {
+		InProgress int `json:"in_progress"`
+		Completed  int `json:"completed"`
+		Failed     int `json:"failed"`
+		Cancelled  int `json:"cancelled"`
+		Total      int `json:"total"`
+	}
+
+	// CreateVectorStoreFileRequest attaches an uploaded file to a vector store.
+	// Attributes are strings, numbers or booleans used by search filters.
+	CreateVectorStoreFileRequest struct {
+		FileId           string            `json:"file_id"`
+		ChunkingStrategy *ChunkingStrategy `json:"chunking_strategy,omitempty"`
+		Attributes       map[string]any    `json:"attributes,omitempty"`
+	}
+
+	// VectorStoreFile is a file attached to a vector store.
+	VectorStoreFile struct {
+		Id               string            `json:"id"`
+		Object           string            `json:"object"`
+		CreatedAt        int64             `json:"created_at"`
+		VectorStoreId    string            `json:"vector_store_id"`
+		UsageBytes       int64             `json:"usage_bytes"`
+		Status           string            `json:"status"`
+		LastError        *JSONErr          `json:"last_error,omitempty"`
+		ChunkingStrategy *ChunkingStrategy `json:"chunking_strategy,omitempty"`
+		Attributes       map[string]any    `json:"attributes,omitempty"`
+	}
+
+	// CreateVectorStoreFileBatchRequest attaches several files at once, with
+	// the same chunking strategy and attributes.
+	CreateVectorStoreFileBatchRequest struct {
+		FileIds          []string          `json:"file_ids"`
+		ChunkingStrategy *ChunkingStrategy `json:"chunking_strategy,omitempty"`
+		Attributes       map[string]any    `json:"attributes,omitempty"`
+	}
+
+	VectorStoreFileBatch struct {
+		Id            string                `json:"id"`
+		Object        string                `json:"object"`
+		CreatedAt     int64                 `json:"created_at"`
+		VectorStoreId string                `json:"vector_store_id"`
+		Status        string                `json:"status"`
+		FileCounts    VectorStoreFileCounts `json:"file_counts"`
+	}
+
+	// ListVectorStoreFilesParams filters and paginates the files of a vector
+	// store or of a file batch.
+	ListVectorStoreFilesParams struct {
+		ListParams
+		// Filter only lists the files with this status.
+		Filter string
+	}
+)
+
+// AutoChunkingStrategy lets the API choose the chunks, currently 800 tokens with an
+// overlap of 400.
+func AutoChunkingStrategy() *ChunkingStrategy {
+	return &ChunkingStrategy{Type: "auto"}
+}
+
+// StaticChunkingStrategy splits files into chunks of maxTokens overlapping by overlapTokens.
+func StaticChunkingStrategy(maxTokens, overlapTokens int) *ChunkingStrategy {
+	return &ChunkingStrategy{Type: "static", Static: &StaticChunking{MaxChunkSizeTokens: maxTokens, ChunkOverlapTokens: overlapTokens}}
+}
+
+func (s *ChunkingStrategy) validate() error {
+	if s == nil || s.Type == "auto" {
+		return nil
+	}
+	if s.Type != "static" || s.Static == nil {
+		return fmt.Errorf("invalid chunking strategy %q", s.Type)
+	}
+	if s.Static.MaxChunkSizeTokens < MinChunkSizeTokens || s.Static.MaxChunkSizeTokens > MaxChunkSizeTokens {
+		return fmt.Errorf("max_chunk_size_tokens must be between %d and %d, got %d", MinChunkSizeTokens, MaxChunkSizeTokens, s.Static.MaxChunkSizeTokens)
+	}
+	if s.Static.ChunkOverlapTokens < 0 || s.Static.ChunkOverlapTokens > s.Static.MaxChunkSizeTokens/2 {
+		return fmt.Errorf("chunk_overlap_tokens must be between 0 and half of max_chunk_size_tokens, got %d", s.Static.ChunkOverlapTokens)
+	}
+	return nil
+}
+
+// Done reports whether the file is no longer being ingested.
+func (f *VectorStoreFile) Done() bool {
+	return f.Status != VectorStoreStatusInProgress
+}
+
+// Done reports whether every file of the batch is no longer being ingested.
+func (b *VectorStoreFileBatch) Done() bool {
+	return b.Status != VectorStoreStatusInProgress
+}
+
+func (p *ListVectorStoreFilesParams) queryParams() []goxios.QueryParam {
+	if p == nil {
+		return nil
+	}
+	params := p.ListParams.queryParams()
+	if p.Filter != "" {
+		params = append(params, goxios.QueryParam{Key: "filter", Value: p.Filter})
+	}
+	return params
+}
+
+// postVectorStores sends body to an endpoint of vector stores and decodes the response.
+func postVectorStores[T any](api OpenAIClient, httpClient HTTPClient, path string, body any) (*T, *OpenAIErr) {
+	res, err := postJSON(api, httpClient, "/vector_stores"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](res)
+}
+
+func CreateVectorStore(api OpenAIClient, httpClient HTTPClient, body *CreateVectorStoreRequest) (*VectorStore, *OpenAIErr) {
+	if err := body.ChunkingStrategy.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	return postVectorStores[VectorStore](api, httpClient, "", body)
+}
+
+func ListVectorStores(api OpenAIClient, httpClient HTTPClient, params *ListParams) (*List[VectorStore], *OpenAIErr) {
+	return getJSON[List[VectorStore]](api, httpClient, "/vector_stores", params.queryParams()...)
+}
+
+func RetrieveVectorStore(api OpenAIClient, httpClient HTTPClient, vectorStoreId string) (*VectorStore, *OpenAIErr) {
+	if vectorStoreId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id is required"))
+	}
+	return getJSON[VectorStore](api, httpClient, "/vector_stores"+pathID(vectorStoreId))
+}
+
+func ModifyVectorStore(api OpenAIClient, httpClient HTTPClient, vectorStoreId string, body *ModifyVectorStoreRequest) (*VectorStore, *OpenAIErr) {
+	if vectorStoreId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id is required"))
+	}
+	return postVectorStores[VectorStore](api, httpClient, pathID(vectorStoreId), body)
+}
+
+func DeleteVectorStore(api OpenAIClient, httpClient HTTPDeleteClient, vectorStoreId string) (*Deletion, *OpenAIErr) {
+	if vectorStoreId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id is required"))
+	}
+	return deleteJSON[Deletion](api, httpClient, "/vector_stores"+pathID(vectorStoreId))
+}
+
+// CreateVectorStoreFile attaches a file to a vector store. The file is ingested
+// in the background: see WaitVectorStoreFile.
+func CreateVectorStoreFile(api OpenAIClient, httpClient HTTPClient, vectorStoreId string, body *CreateVectorStoreFileRequest) (*VectorStoreFile, *OpenAIErr) {
+	if vectorStoreId == "" || body.FileId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and file_id are required"))
+	}
+	if err := body.ChunkingStrategy.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	return postVectorStores[VectorStoreFile](api, httpClient, pathID(vectorStoreId)+"/files", body)
+}
+
+func ListVectorStoreFiles(api OpenAIClient, httpClient HTTPClient, vectorStoreId string, params *ListVectorStoreFilesParams) (*List[VectorStoreFile], *OpenAIErr) {
+	if vectorStoreId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id is required"))
+	}
+	return getJSON[List[VectorStoreFile]](api, httpClient, "/vector_stores"+pathID(vectorStoreId)+"/files", params.queryParams()...)
+}
+
+func RetrieveVectorStoreFile(api OpenAIClient, httpClient HTTPClient, vectorStoreId, fileId string) (*VectorStoreFile, *OpenAIErr) {
+	if vectorStoreId == "" || fileId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and file id are required"))
+	}
+	return getJSON[VectorStoreFile](api, httpClient, "/vector_stores"+pathID(vectorStoreId)+"/files"+pathID(fileId))
+}
+
+// UpdateVectorStoreFileAttributes replaces the attributes of a file.
+func UpdateVectorStoreFileAttributes(api OpenAIClient, httpClient HTTPClient, vectorStoreId, fileId string, attributes map[string]any) (*VectorStoreFile, *OpenAIErr) {
+	if vectorStoreId == "" || fileId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and file id are required"))
+	}
+	return postVectorStores[VectorStoreFile](api, httpClient, pathID(vectorStoreId)+"/files"+pathID(fileId), map[string]any{"attributes": attributes})
+}
+
+// DeleteVectorStoreFile detaches a file from a vector store. The file itself
+// is deleted with DeleteFile.
+func DeleteVectorStoreFile(api OpenAIClient, httpClient HTTPDeleteClient, vectorStoreId, fileId string) (*Deletion, *OpenAIErr) {
+	if vectorStoreId == "" || fileId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and file id are required"))
+	}
+	return deleteJSON[Deletion](api, httpClient, "/vector_stores"+pathID(vectorStoreId)+"/files"+pathID(fileId))
+}
+
+func CreateVectorStoreFileBatch(api OpenAIClient, httpClient HTTPClient, vectorStoreId string, body *CreateVectorStoreFileBatchRequest) (*VectorStoreFileBatch, *OpenAIErr) {
+	if vectorStoreId == "" || len(body.FileIds) == 0 {
+		return nil, errInvalidRequest(errors.New("vector store id and file_ids are required"))
+	}
+	if err := body.ChunkingStrategy.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	return postVectorStores[VectorStoreFileBatch](api, httpClient, pathID(vectorStoreId)+"/file_batches", body)
+}
+
+func RetrieveVectorStoreFileBatch(api OpenAIClient, httpClient HTTPClient, vectorStoreId, batchId string) (*VectorStoreFileBatch, *OpenAIErr) {
+	if vectorStoreId == "" || batchId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and batch id are required"))
+	}
+	return getJSON[VectorStoreFileBatch](api, httpClient, "/vector_stores"+pathID(vectorStoreId)+"/file_batches"+pathID(batchId))
+}
+
+// CancelVectorStoreFileBatch cancels the ingestion of the files of a batch not
+// processed yet.
+func CancelVectorStoreFileBatch(api OpenAIClient, httpClient HTTPClient, vectorStoreId, batchId string) (*VectorStoreFileBatch, *OpenAIErr) {
+	if vectorStoreId == "" || batchId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and batch id are required"))
+	}
+	return postVectorStores[VectorStoreFileBatch](api, httpClient, pathID(vectorStoreId)+"/file_batches"+pathID(batchId)+"/cancel", struct{}{})
+}
+
+func ListVectorStoreFileBatchFiles(api OpenAIClient, httpClient HTTPClient, vectorStoreId, batchId string, params *ListVectorStoreFilesParams) (*List[VectorStoreFile], *OpenAIErr) {
+	if vectorStoreId == "" || batchId == "" {
+		return nil, errInvalidRequest(errors.New("vector store id and batch id are required"))
+	}
+	return getJSON[List[VectorStoreFile]](api, httpClient, "/vector_stores"+pathID(vectorStoreId)+"/file_batches"+pathID(batchId)+"/files", params.queryParams()...)
+}
+
+// VectorStorePollOpts configures the helpers waiting for files to be ingested.
+// Zero values use the defaults.
+type VectorStorePollOpts struct {
+	// Interval is the first delay between two polls, 1s by default. It doubles
+	// after every poll up to MaxInterval, 30s by default.
+	Interval, MaxInterval time.Duration
+	// MaxRetries bounds the consecutive failed polls, 5 by default.
+	MaxRetries int
+}
+
+// Polling defaults of the vector store helpers. Most files are ingested in seconds.
+const (
+	defaultVectorStorePollInterval    = time.Second
+	defaultVectorStorePollMaxInterval = 30 * time.Second
+)
+
+func (o *VectorStorePollOpts) intervals() (time.Duration, time.Duration, int) {
+	if o == nil {
+		o = &VectorStorePollOpts{}
+	}
+	interval, maxInterval := o.Interval, o.MaxInterval
+	if interval <= 0 {
+		interval = defaultVectorStorePollInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultVectorStorePollMaxInterval
+	}
+	return interval, maxInterval, o.MaxRetries
+}
+
+// WaitVectorStoreFile polls a file until it is ingested, and returns it whatever
+// its final status: check Status and LastError. Canceling ctx stops waiting.
+func WaitVectorStoreFile(ctx context.Context, api OpenAIClient, httpClient HTTPClient, vectorStoreId, fileId string, opts *VectorStorePollOpts) (*VectorStoreFile, *OpenAIErr) {
+	interval, maxInterval, maxRetries := opts.intervals()
+	return poll(ctx, interval, maxInterval, maxRetries, func() (*VectorStoreFile, *OpenAIErr) {
+		return RetrieveVectorStoreFile(api, httpClient, vectorStoreId, fileId)
+	}, (*VectorStoreFile).Done)
+}
+
+// WaitVectorStoreFileBatch polls a file batch until every file is ingested, and
+// returns it whatever its final status: FileCounts tells how many files failed.
+// Canceling ctx stops waiting, not the batch.
+func WaitVectorStoreFileBatch(ctx context.Context, api OpenAIClient, httpClient HTTPClient, vectorStoreId, batchId string, opts *VectorStorePollOpts) (*VectorStoreFileBatch, *OpenAIErr) {
+	interval, maxInterval, maxRetries := opts.intervals()
+	return poll(ctx, interval, maxInterval, maxRetries, func() (*VectorStoreFileBatch, *OpenAIErr) {
+		return RetrieveVectorStoreFileBatch(api, httpClient, vectorStoreId, batchId)
+	}, (*VectorStoreFileBatch).Done)
+}
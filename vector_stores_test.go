@@ -0,0 +1,161 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// MockVectorStoresHTTPClient ingests a file batch in two polls and answers
+// searches with a fixed page.
+type MockVectorStoresHTTPClient struct {
+	bodies map[string]string
+	polls  int
+}
+
+func (c *MockVectorStoresHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	path := strings.TrimPrefix(url, "https://fake.api.openai.com/v1")
+	b, err := io.ReadAll(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.bodies[path] = string(b)
+	switch path {
+	case "/vector_stores":
+		return jsonResponse(http.StatusOK, `{"id":"vs_1","object":"vector_store","name":"docs","status":"completed","file_counts":{"total":0}}`), nil
+	case "/vector_stores/vs_1/file_batches":
+		return jsonResponse(http.StatusOK, `{"id":"vsfb_1","object":"vector_store.file_batch","vector_store_id":"vs_1","status":"in_progress","file_counts":{"in_progress":2,"total":2}}`), nil
+	case "/vector_stores/vs_1/search":
+		return jsonResponse(http.StatusOK, `{"object":"vector_store.search_results.page","search_query":"refund policy","data":[
+			{"file_id":"file-2","filename":"refunds.md","score":0.91,"attributes":{"year":2024,"lang":"en"},"content":[{"type":"text","text":"Refunds within 30 days."}]},
+			{"file_id":"file-3","filename":"returns.md","score":0.74,"attributes":{"year":2023,"lang":"en"},"content":[{"type":"text","text":"Returns are free."},{"type":"text","text":"Ship within 14 days."}]}
+		],"has_more":false,"next_page":null}`), nil
+	}
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func (c *MockVectorStoresHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	if strings.HasSuffix(url, "/vector_stores/vs_1/file_batches/vsfb_1") {
+		c.polls++
+		if c.polls < 2 {
+			return jsonResponse(http.StatusOK, `{"id":"vsfb_1","status":"in_progress","file_counts":{"in_progress":1,"completed":1,"total":2}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"id":"vsfb_1","status":"completed","file_counts":{"completed":1,"failed":1,"total":2}}`), nil
+	}
+	return jsonResponse(http.StatusNotFound, `{"error":{"message":"not found","type":"invalid_request_error"}}`), nil
+}
+
+func TestVectorStores(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockVectorStoresHTTPClient{bodies: map[string]string{}}
+	store, err := CreateVectorStore(api, httpClient, &CreateVectorStoreRequest{Name: "docs", ChunkingStrategy: StaticChunkingStrategy(400, 100)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"name":"docs","chunking_strategy":{"type":"static","static":{"max_chunk_size_tokens":400,"chunk_overlap_tokens":100}}}`; httpClient.bodies["/vector_stores"] != want {
+		t.Errorf("expected %s, got %s", want, httpClient.bodies["/vector_stores"])
+	}
+	batch, err := CreateVectorStoreFileBatch(api, httpClient, store.Id, &CreateVectorStoreFileBatchRequest{FileIds: []string{"file-2", "file-3"}, Attributes: map[string]any{"lang": "en"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	batch, err = WaitVectorStoreFileBatch(context.Background(), api, httpClient, store.Id, batch.Id, &VectorStorePollOpts{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batch.Status != VectorStoreStatusCompleted || batch.FileCounts.Failed != 1 || httpClient.polls != 2 {
+		t.Errorf("unexpected batch %+v after %d polls", batch, httpClient.polls)
+	}
+
+	filter := FilterAnd(FilterEq("lang", "en"), FilterOr(FilterGte("year", 2023), FilterEq("pinned", true)))
+	results, err := SearchVectorStore(api, httpClient, store.Id, &VectorStoreSearchRequest{Query: "refund policy", Filters: filter, MaxNumResults: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"query":"refund policy","filters":{"type":"and","filters":[{"type":"eq","key":"lang","value":"en"},{"type":"or","filters":[{"type":"gte","key":"year","value":2023},{"type":"eq","key":"pinned","value":true}]}]},"max_num_results":5}`
+	if httpClient.bodies["/vector_stores/vs_1/search"] != want {
+		t.Errorf("expected %s, got %s", want, httpClient.bodies["/vector_stores/vs_1/search"])
+	}
+	var decoded AttributeFilter
+	b, _ := json.Marshal(filter)
+	if err := json.Unmarshal(b, &decoded); err != nil || len(decoded.Filters) != 2 || decoded.Filters[1].Filters[0].Key != "year" {
+		t.Errorf("unexpected decoded filter %+v, error %v", decoded, err)
+	}
+	if len(results.SearchQuery) != 1 || results.SearchQuery[0] != "refund policy" {
+		t.Errorf("unexpected search query %v", results.SearchQuery)
+	}
+	hits := results.Hits()
+	if len(hits) != 2 || hits[1].Text != "Returns are free.\nShip within 14 days." || hits[0].Attributes["year"] != 2024.0 {
+		t.Errorf("unexpected hits %+v", hits)
+	}
+
+	documents := []SearchDocument{
+		{Id: "file-1", Embedding: []float64{0, 1}, Attributes: map[string]any{"lang": "pt", "year": 2024}},
+		{Id: "file-2", Embedding: []float64{1, 0.1}, Attributes: map[string]any{"lang": "en", "year": 2024}},
+		{Id: "file-3", Embedding: []float64{0.5, 0.5}, Attributes: map[string]any{"lang": "en", "year": 2023}},
+		{Id: "file-4", Embedding: []float64{1, 0}, Attributes: map[string]any{"lang": "en", "year": 2020}},
+	}
+	local := LocalSearch([]float64{1, 0}, documents, filter, 2)
+	if len(local) != 2 || local[0].Id != "file-2" || local[1].Id != "file-3" {
+		t.Errorf("unexpected local hits %+v", local)
+	}
+	if recall := SearchRecall(local, hits); recall != 1 {
+		t.Errorf("expected a recall of 1, got %f", recall)
+	}
+	if recall := SearchRecall(LocalSearch([]float64{1, 0}, documents, nil, 2), hits); recall != 0.5 {
+		t.Errorf("expected a recall of 0.5 without filter, got %f", recall)
+	}
+}
+
+func TestVectorStoreValidation(t *testing.T) {
+	api := MockClient{"https://fake.api.openai.com/v1"}
+	httpClient := &MockVectorStoresHTTPClient{bodies: map[string]string{}}
+	for _, strategy := range []*ChunkingStrategy{StaticChunkingStrategy(50, 0), StaticChunkingStrategy(800, 401), {Type: "semantic"}} {
+		if _, err := CreateVectorStore(api, httpClient, &CreateVectorStoreRequest{ChunkingStrategy: strategy}); err == nil {
+			t.Errorf("expected an error for %+v", strategy)
+		}
+	}
+	if _, err := CreateVectorStore(api, httpClient, &CreateVectorStoreRequest{ChunkingStrategy: AutoChunkingStrategy()}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, filter := range []*AttributeFilter{FilterEq("", "x"), FilterGt("year", []int{1}), FilterAnd(), {Type: "like", Key: "a", Value: "b"}} {
+		if _, err := SearchVectorStore(api, httpClient, "vs_1", &VectorStoreSearchRequest{Query: "q", Filters: filter}); err == nil {
+			t.Errorf("expected an error for %+v", filter)
+		}
+	}
+	if len(httpClient.bodies) != 1 {
+		t.Errorf("expected invalid requests not to be sent, got %v", httpClient.bodies)
+	}
+}
+
+func TestAttributeFilterMatch(t *testing.T) {
+	attributes := map[string]any{"lang": "en", "year": 2024.0, "draft": false}
+	tests := []struct {
+		filter *AttributeFilter
+		want   bool
+	}{
+		{FilterEq("lang", "en"), true},
+		{FilterEq("year", 2024), true},
+		{FilterNe("lang", "pt"), true},
+		{FilterEq("draft", false), true},
+		{FilterGt("year", 2024), false},
+		{FilterLte("year", 2024), true},
+		{FilterLt("lang", 3), false},
+		{FilterEq("missing", "x"), false},
+		{FilterEq("year", "2024"), false},
+		{FilterOr(FilterEq("lang", "pt"), FilterGte("year", 2000)), true},
+		{FilterAnd(FilterEq("lang", "en"), FilterEq("draft", true)), false},
+	}
+	for _, test := range tests {
+		if got := test.filter.Match(attributes); got != test.want {
+			b, _ := json.Marshal(test.filter)
+			t.Errorf("%s: expected %v, got %v", b, test.want, got)
+		}
+	}
+}
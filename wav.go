@@ -0,0 +1,243 @@
+package openai
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// PCMFormat describes uncompressed little-endian PCM audio.
+type PCMFormat struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int // 8, 16, 24 or 32.
+}
+
+// PCM16Mono24kHz is the format of the pcm output of the speech API.
+var PCM16Mono24kHz = PCMFormat{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+
+// BlockAlign returns the size in bytes of one sample frame, all channels included.
+func (f PCMFormat) BlockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+// ByteRate returns the number of bytes per second of audio.
+func (f PCMFormat) ByteRate() int {
+	return f.SampleRate * f.BlockAlign()
+}
+
+// Duration returns the duration of n bytes of audio.
+func (f PCMFormat) Duration(n int) time.Duration {
+	return time.Duration(float64(n) / float64(f.ByteRate()) * float64(time.Second))
+}
+
+// Bytes returns the number of bytes of d of audio, rounded down to a whole sample frame.
+func (f PCMFormat) Bytes(d time.Duration) int {
+	frames := int(d.Seconds() * float64(f.SampleRate))
+	return frames * f.BlockAlign()
+}
+
+func (f PCMFormat) validate() error {
+	switch f.BitsPerSample {
+	case 8, 16, 24, 32:
+	default:
+		return fmt.Errorf("unsupported bits per sample %d", f.BitsPerSample)
+	}
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return fmt.Errorf("invalid pcm format %+v", f)
+	}
+	return nil
+}
+
+// PCMAudio is uncompressed audio held in memory.
+type PCMAudio struct {
+	PCMFormat
+	Data []byte
+}
+
+// Duration returns the duration of the audio.
+func (a *PCMAudio) Duration() time.Duration {
+	return a.PCMFormat.Duration(len(a.Data))
+}
+
+// amplitude returns the normalized absolute amplitude, between 0 and 1, of the
+// sample at byte offset i.
+func (f PCMFormat) amplitude(data []byte, i int) float64 {
+	switch f.BitsPerSample {
+	case 8:
+		return math.Abs(float64(int(data[i])-128)) / 128
+	case 16:
+		return math.Abs(float64(int16(binary.LittleEndian.Uint16(data[i:])))) / (1 << 15)
+	case 24:
+		v := int32(uint32(data[i])<<8|uint32(data[i+1])<<16|uint32(data[i+2])<<24) >> 8
+		return math.Abs(float64(v)) / (1 << 23)
+	default:
+		return math.Abs(float64(int32(binary.LittleEndian.Uint32(data[i:])))) / (1 << 31)
+	}
+}
+
+// rms returns the root mean square amplitude of data, between 0 and 1.
+func (f PCMFormat) rms(data []byte) float64 {
+	step := f.BitsPerSample / 8
+	n := len(data) / step
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i+step <= len(data); i += step {
+		a := f.amplitude(data, i)
+		sum += a * a
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+const (
+	wavHeaderSize   = 44
+	wavFormatPCM    = 1
+	wavFormatExtend = 0xFFFE
+	// wavUnknownSize is written as the data size of streamed WAV files, whose length
+	// is not known when the header is written. Most players read until EOF.
+	wavUnknownSize = math.MaxUint32
+)
+
+// ReadWAV reads a PCM WAV file. A data chunk with an unknown size is read until EOF.
+func ReadWAV(r io.Reader) (*PCMAudio, error) {
+	format, size, err := readWAVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if size == wavUnknownSize {
+		data, err = io.ReadAll(r)
+	} else {
+		data = make([]byte, size)
+		_, err = io.ReadFull(r, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wav: %w", err)
+	}
+	data = data[:len(data)-len(data)%format.BlockAlign()]
+	return &PCMAudio{PCMFormat: format, Data: data}, nil
+}
+
+// readWAVHeader reads a PCM WAV file up to the start of its samples and returns
+// their format and size. The size is wavUnknownSize for streamed files.
+func readWAVHeader(r io.Reader) (PCMFormat, uint32, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return PCMFormat{}, 0, fmt.Errorf("wav: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return PCMFormat{}, 0, errors.New("wav: not a RIFF WAVE file")
+	}
+	var format *PCMFormat
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return PCMFormat{}, 0, fmt.Errorf("wav: missing data chunk: %w", err)
+		}
+		id, size := string(header[0:4]), binary.LittleEndian.Uint32(header[4:8])
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return PCMFormat{}, 0, errors.New("wav: invalid fmt chunk")
+			}
+			b := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return PCMFormat{}, 0, fmt.Errorf("wav: %w", err)
+			}
+			audioFormat := binary.LittleEndian.Uint16(b[0:2])
+			if audioFormat == wavFormatExtend && size >= 26 {
+				audioFormat = binary.LittleEndian.Uint16(b[24:26])
+			}
+			if audioFormat != wavFormatPCM {
+				return PCMFormat{}, 0, fmt.Errorf("wav: unsupported audio format %d, only PCM is supported", audioFormat)
+			}
+			format = &PCMFormat{
+				Channels:      int(binary.LittleEndian.Uint16(b[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(b[4:8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(b[14:16])),
+			}
+			if err := format.validate(); err != nil {
+				return PCMFormat{}, 0, fmt.Errorf("wav: %w", err)
+			}
+		case "data":
+			if format == nil {
+				return PCMFormat{}, 0, errors.New("wav: data chunk before fmt chunk")
+			}
+			if size == 0 {
+				size = wavUnknownSize
+			}
+			return *format, size, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return PCMFormat{}, 0, fmt.Errorf("wav: %w", err)
+			}
+		}
+	}
+}
+
+// writeWAVHeader writes the header of a PCM WAV file with dataSize bytes of audio.
+func writeWAVHeader(w io.Writer, format PCMFormat, dataSize uint32) error {
+	riffSize := uint32(wavUnknownSize)
+	if dataSize != wavUnknownSize {
+		riffSize = dataSize + wavHeaderSize - 8
+	}
+	header := make([]byte, 0, wavHeaderSize)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, riffSize)
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, wavFormatPCM)
+	header = binary.LittleEndian.AppendUint16(header, uint16(format.Channels))
+	header = binary.LittleEndian.AppendUint32(header, uint32(format.SampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(format.ByteRate()))
+	header = binary.LittleEndian.AppendUint16(header, uint16(format.BlockAlign()))
+	header = binary.LittleEndian.AppendUint16(header, uint16(format.BitsPerSample))
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, dataSize)
+	_, err := w.Write(header)
+	return err
+}
+
+// WriteWAV writes data as a PCM WAV file.
+func WriteWAV(w io.Writer, format PCMFormat, data []byte) error {
+	if err := writeWAVHeader(w, format, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// FinalizeWAV rewrites the sizes in the header of a WAV file written with an
+// unknown size, such as streamed speech, once the whole file has been written.
+// The header must be the 44 byte header written by this package or the API.
+func FinalizeWAV(f io.WriteSeeker) error {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if end < wavHeaderSize || end-8 > math.MaxUint32 {
+		return fmt.Errorf("wav: invalid file size %d", end)
+	}
+	sizes := []struct {
+		offset int64
+		value  uint32
+	}{
+		{4, uint32(end - 8)},
+		{wavHeaderSize - 4, uint32(end - wavHeaderSize)},
+	}
+	for _, size := range sizes {
+		if _, err := f.Seek(size.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if err := binary.Write(f, binary.LittleEndian, size.value); err != nil {
+			return err
+		}
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,272 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+// webSocketGUID is appended to the handshake key to compute its accept value.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage bounds the size of a message read from a WebSocket.
+const maxWebSocketMessage = 16 << 20
+
+// WebSocket frame opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// errWebSocketClosed is returned by reads once the peer closed the connection.
+var errWebSocketClosed = errors.New("websocket: connection closed")
+
+// wsConn is a minimal WebSocket connection (RFC 6455) exchanging whole
+// messages. Control frames are answered while reading. Writes are safe for
+// concurrent use; reads are not.
+type wsConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	client bool
+	mu     sync.Mutex
+}
+
+// newWSConn wraps an upgraded connection. Client connections mask their frames.
+func newWSConn(conn net.Conn, r *bufio.Reader, client bool) *wsConn {
+	if r == nil {
+		r = bufio.NewReader(conn)
+	}
+	return &wsConn{conn: conn, r: r, client: client}
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept value of a handshake key.
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// dialWebSocket opens a WebSocket connection to a ws or wss URL, sending
+// headers with the handshake. A handshake refused by the server returns the
+// error of its response.
+func dialWebSocket(ctx context.Context, rawURL string, headers []goxios.Header, tlsConfig *tls.Config) (*wsConn, *OpenAIErr) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), map[string]string{"ws": "80", "wss": "443"}[u.Scheme])
+	}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	case "wss":
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		conn, err = (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, errInvalidRequest(fmt.Errorf("unsupported websocket scheme %q", u.Scheme))
+	}
+	if err != nil {
+		return nil, errCannotSendRequest(err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{}}
+	for _, h := range headers {
+		req.Header.Set(h.Key, fmt.Sprint(h.Value))
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, errCannotSendRequest(asContextErr(ctx, err))
+	}
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, errCannotReadBody(asContextErr(ctx, err))
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		return nil, openaiHttpError(res)
+	}
+	if res.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(req.Header.Get("Sec-WebSocket-Key")) {
+		conn.Close()
+		return nil, errCannotReadBody(errors.New("websocket: invalid Sec-WebSocket-Accept"))
+	}
+	return newWSConn(conn, r, true), nil
+}
+
+// asContextErr returns the error of ctx when it is done, as the connection was
+// closed because of it, and err otherwise.
+func asContextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readMessage returns the next text or binary message, answering pings and
+// close frames. It returns errWebSocketClosed once the peer closed.
+func (c *wsConn) readMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			code := payload
+			if len(code) > 2 {
+				code = code[:2]
+			}
+			c.writeFrame(wsClose, code)
+			return 0, nil, errWebSocketClosed
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		case wsText, wsBinary:
+			if opcode != 0 {
+				return 0, nil, errors.New("websocket: expected a continuation frame")
+			}
+			opcode = op
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+		if len(message)+len(payload) > maxWebSocketMessage {
+			return 0, nil, fmt.Errorf("websocket: message larger than %d bytes", maxWebSocketMessage)
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op, masked := header[0]&0x80 != 0, header[0]&0x0f, header[1]&0x80 != 0
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("websocket: frame larger than %d bytes", maxWebSocketMessage)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame sends payload as a single frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|opcode)
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// close sends a normal closure frame and closes the connection.
+func (c *wsConn) close() error {
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrame(wsClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}
@@ -1,67 +1,357 @@
 package openai
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
 	"io"
-	"mime/multipart"
+	"mime"
+	"net/http"
 	"os"
-
-	"github.com/Simplou/goxios"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 const DefaultTranscriptionModel = "whisper-1"
 
+// Formats of the transcription output.
+const (
+	TranscriptionFormatJSON        = "json"
+	TranscriptionFormatText        = "text"
+	TranscriptionFormatSRT         = "srt"
+	TranscriptionFormatVerboseJSON = "verbose_json"
+	TranscriptionFormatVTT         = "vtt"
+)
+
+// Timestamp granularities of verbose_json transcriptions.
+const (
+	TimestampGranularityWord    = "word"
+	TimestampGranularitySegment = "segment"
+)
+
+// MaxAudioFileSize is the largest audio file accepted by the audio endpoints.
+const MaxAudioFileSize = 25 << 20
+
+// audioContentTypes maps the supported audio file extensions to their content type.
+var audioContentTypes = map[string]string{
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".mp4":  "audio/mp4",
+	".mpeg": "audio/mpeg",
+	".mpga": "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".wav":  "audio/wav",
+	".webm": "audio/webm",
+}
+
+// supportedAudioContentTypes lists the content types accepted besides the ones in audioContentTypes.
+var supportedAudioContentTypes = []string{
+	"audio/x-flac", "audio/m4a", "audio/x-m4a", "audio/mp3", "audio/x-wav", "audio/wave",
+	"audio/vnd.wave", "video/mp4", "video/mpeg", "video/webm", "application/octet-stream",
+}
+
 type (
 	TranscriptionsRequestBody struct {
 		Model, Filename, AudioFilePath string
+		// Audio is read instead of AudioFilePath when set. Filename is required to tell the
+		// API the audio format.
+		Audio io.Reader
+		// ContentType of Audio. Guessed from the Filename extension when empty.
+		ContentType string
+		// Language of the input audio in ISO-639-1 format. Improves accuracy and latency.
+		Language string
+		// Prompt guides the model's style or continues a previous audio segment. It should match the audio language.
+		Prompt string
+		// Temperature between 0 and 1. When nil the model chooses it automatically.
+		Temperature *float64
+		// ResponseFormat is one of the TranscriptionFormat constants, json by default.
+		ResponseFormat string
+		// TimestampGranularities requires the verbose_json response format.
+		TimestampGranularities []string
 	}
 
+	// TranscriptionResponse is the transcription result. For the text, srt and vtt
+	// formats Text holds the raw output and the other fields are empty.
 	TranscriptionResponse struct {
-		Text string `json:"text"`
+		Text     string                 `json:"text"`
+		Task     string                 `json:"task,omitempty"`
+		Language string                 `json:"language,omitempty"`
+		Duration float64                `json:"duration,omitempty"`
+		Segments []TranscriptionSegment `json:"segments,omitempty"`
+		Words    []TranscriptionWord    `json:"words,omitempty"`
+	}
+
+	// TranscriptionSegment is a segment of a verbose_json transcription. Times are in seconds.
+	TranscriptionSegment struct {
+		Id               int     `json:"id"`
+		Seek             int     `json:"seek"`
+		Start            float64 `json:"start"`
+		End              float64 `json:"end"`
+		Text             string  `json:"text"`
+		Tokens           []int   `json:"tokens"`
+		Temperature      float64 `json:"temperature"`
+		AvgLogprob       float64 `json:"avg_logprob"`
+		CompressionRatio float64 `json:"compression_ratio"`
+		NoSpeechProb     float64 `json:"no_speech_prob"`
+	}
+
+	// TranscriptionWord is a word of a verbose_json transcription with word timestamps.
+	TranscriptionWord struct {
+		Word  string  `json:"word"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
 	}
 )
 
-func Transcription(api OpenAIClient, httpClient HTTPClient, body *TranscriptionsRequestBody) (*TranscriptionResponse, *OpenAIErr) {
-	file, err := os.Open(body.AudioFilePath)
-	if err != nil {
-		return nil, errCannotOpenFile(err)
+// isJSONTranscriptionFormat reports whether the output of format is JSON encoded.
+func isJSONTranscriptionFormat(format string) bool {
+	return format == "" || format == TranscriptionFormatJSON || format == TranscriptionFormatVerboseJSON
+}
+
+// validateAudioOptions checks the options shared by transcriptions and translations.
+func validateAudioOptions(responseFormat string, temperature *float64) error {
+	switch responseFormat {
+	case "", TranscriptionFormatJSON, TranscriptionFormatText, TranscriptionFormatSRT, TranscriptionFormatVerboseJSON, TranscriptionFormatVTT:
+	default:
+		return fmt.Errorf("unsupported response_format %q", responseFormat)
+	}
+	if t := temperature; t != nil && (*t < 0 || *t > 1) {
+		return fmt.Errorf("temperature must be between 0 and 1, got %v", *t)
 	}
-	defer file.Close()
+	return nil
+}
 
-	b := &bytes.Buffer{}
-	writer := multipart.NewWriter(b)
+func audioFormFields(model, prompt, responseFormat string, temperature *float64) []formField {
+	fields := []formField{
+		{"model", model},
+		{"prompt", prompt},
+		{"response_format", responseFormat},
+	}
+	if temperature != nil {
+		fields = append(fields, formField{"temperature", strconv.FormatFloat(*temperature, 'f', -1, 64)})
+	}
+	return fields
+}
 
-	part, err := writer.CreateFormFile("file", body.Filename)
-	if err != nil {
-		return nil, errCannotCreateFormFile(err)
+func (body *TranscriptionsRequestBody) validate() error {
+	if err := validateAudioOptions(body.ResponseFormat, body.Temperature); err != nil {
+		return err
+	}
+	if len(body.TimestampGranularities) > 0 && body.ResponseFormat != TranscriptionFormatVerboseJSON {
+		return errors.New("timestamp_granularities requires the verbose_json response format")
+	}
+	for _, granularity := range body.TimestampGranularities {
+		if granularity != TimestampGranularityWord && granularity != TimestampGranularitySegment {
+			return fmt.Errorf("unsupported timestamp granularity %q", granularity)
+		}
+	}
+	return nil
+}
+
+func (body *TranscriptionsRequestBody) formFields() []formField {
+	fields := audioFormFields(body.Model, body.Prompt, body.ResponseFormat, body.Temperature)
+	fields = append(fields, formField{"language", body.Language})
+	for _, granularity := range body.TimestampGranularities {
+		fields = append(fields, formField{"timestamp_granularities[]", granularity})
 	}
-	_, err = io.Copy(part, file)
+	return fields
+}
+
+// audioFile is an audio upload checked against the format and size limits of the API.
+type audioFile struct {
+	filename, contentType string
+	content               io.Reader
+	closer                io.Closer
+}
+
+// openAudio validates and opens the audio of a request, either from audio or from audioFilePath.
+func openAudio(filename, audioFilePath string, audio io.Reader, contentType string) (*audioFile, *OpenAIErr) {
+	if filename == "" && audioFilePath != "" {
+		filename = filepath.Base(audioFilePath)
+	}
+	contentType, err := audioContentType(filename, contentType)
 	if err != nil {
-		return nil, errCannotCopyFileContent(err)
+		return nil, errInvalidAudio(err)
+	}
+	a := &audioFile{filename: filename, contentType: contentType, content: audio}
+	if a.content == nil {
+		if audioFilePath == "" {
+			return nil, errInvalidAudio(errors.New("either Audio or AudioFilePath is required"))
+		}
+		file, err := os.Open(audioFilePath)
+		if err != nil {
+			return nil, errCannotOpenFile(err)
+		}
+		a.content, a.closer = file, file
 	}
+	if size, ok := readerSize(a.content); ok && size > MaxAudioFileSize {
+		a.Close()
+		return nil, errInvalidAudio(fmt.Errorf("%s: audio must be at most 25MB, got %d bytes", filename, size))
+	}
+	a.content = &maxSizeReader{r: a.content, n: MaxAudioFileSize, filename: filename}
+	return a, nil
+}
 
-	writer.WriteField("model", body.Model)
+func (a *audioFile) formFile() formFile {
+	return formFile{Field: "file", Filename: a.filename, ContentType: a.contentType, Content: a.content}
+}
 
-	writer.Close()
+func (a *audioFile) Close() {
+	if a.closer != nil {
+		a.closer.Close()
+	}
+}
 
-	api.AddHeader(goxios.Header{Key: "Content-Type", Value: writer.FormDataContentType()})
-	requestOptions := goxios.RequestOpts{
-		Headers: api.Headers(),
-		Body:    b,
+// audioContentType checks that the audio format is supported and returns its content type.
+func audioContentType(filename, contentType string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	extContentType, ok := audioContentTypes[ext]
+	if !ok {
+		return "", fmt.Errorf("%q: unsupported audio format, expected one of flac, m4a, mp3, mp4, mpeg, mpga, oga, ogg, wav or webm", filename)
 	}
-	res, err := httpClient.Post(api.BaseURL()+"/audio/transcriptions", &requestOptions)
+	if contentType == "" {
+		return extContentType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return nil, errCannotSendRequest(err)
+		return "", err
+	}
+	for _, supported := range audioContentTypes {
+		if mediaType == supported {
+			return contentType, nil
+		}
 	}
-	defer res.Body.Close()
+	if contains(supportedAudioContentTypes, mediaType) {
+		return contentType, nil
+	}
+	return "", fmt.Errorf("unsupported audio content type %q", contentType)
+}
 
-	result := new(TranscriptionResponse)
+// readerSize returns the number of bytes left in r when it can be known without reading it.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		if seeker, ok := r.(io.Seeker); ok {
+			if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+				return info.Size() - offset, true
+			}
+		}
+		return info.Size(), true
+	}
+	return 0, false
+}
 
-	if err := goxios.DecodeJSON(res.Body, result); err != nil {
-		return nil, errCannotDecodeJSON(err)
+// maxSizeReader fails once more than n bytes are read, so oversized uploads
+// from readers of unknown size are aborted instead of sent in full.
+type maxSizeReader struct {
+	r        io.Reader
+	n        int64
+	filename string
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+	if m.n < 0 {
+		return n, errInvalidAudio(fmt.Errorf("%s: audio must be at most 25MB", m.filename))
+	}
+	return n, err
+}
+
+// Transcription transcribes audio into the input language. The audio is streamed
+// to the API, so memory use does not grow with the file size.
+func Transcription(api OpenAIClient, httpClient HTTPClient, body *TranscriptionsRequestBody) (*TranscriptionResponse, *OpenAIErr) {
+	raw, openaiErr := TranscriptionRaw(api, httpClient, body)
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	return decodeAudioText(raw, body.ResponseFormat)
+}
+
+// TranscriptionRaw works like Transcription but returns the output unparsed, in
+// the requested response format. The caller must close it.
+func TranscriptionRaw(api OpenAIClient, httpClient HTTPClient, body *TranscriptionsRequestBody) (io.ReadCloser, *OpenAIErr) {
+	if err := body.validate(); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	audio, openaiErr := openAudio(body.Filename, body.AudioFilePath, body.Audio, body.ContentType)
+	if openaiErr != nil {
+		return nil, openaiErr
 	}
-	if res.StatusCode != 200 {
-		return nil, openaiHttpError(res)
+	return postAudio(api, httpClient, "/audio/transcriptions", body.formFields(), audio)
+}
+
+type (
+	// TranslationsRequestBody represents the request body for the translations API,
+	// which translates audio into English.
+	TranslationsRequestBody struct {
+		Model, Filename, AudioFilePath string
+		// Audio is read instead of AudioFilePath when set. Filename is required to tell the
+		// API the audio format.
+		Audio io.Reader
+		// ContentType of Audio. Guessed from the Filename extension when empty.
+		ContentType string
+		// Prompt guides the model's style or continues a previous audio segment. It should be in English.
+		Prompt string
+		// Temperature between 0 and 1. When nil the model chooses it automatically.
+		Temperature *float64
+		// ResponseFormat is one of the TranscriptionFormat constants, json by default.
+		ResponseFormat string
+	}
+
+	// TranslationResponse is the English text of a translation. Translations have no word timestamps.
+	TranslationResponse = TranscriptionResponse
+)
+
+// Translation translates audio into English text.
+func Translation(api OpenAIClient, httpClient HTTPClient, body *TranslationsRequestBody) (*TranslationResponse, *OpenAIErr) {
+	raw, openaiErr := TranslationRaw(api, httpClient, body)
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	return decodeAudioText(raw, body.ResponseFormat)
+}
+
+// TranslationRaw works like Translation but returns the output unparsed, in
+// the requested response format. The caller must close it.
+func TranslationRaw(api OpenAIClient, httpClient HTTPClient, body *TranslationsRequestBody) (io.ReadCloser, *OpenAIErr) {
+	if err := validateAudioOptions(body.ResponseFormat, body.Temperature); err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	audio, openaiErr := openAudio(body.Filename, body.AudioFilePath, body.Audio, body.ContentType)
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	fields := audioFormFields(body.Model, body.Prompt, body.ResponseFormat, body.Temperature)
+	return postAudio(api, httpClient, "/audio/translations", fields, audio)
+}
+
+// postAudio uploads audio with fields to path and returns the response body.
+func postAudio(api OpenAIClient, httpClient HTTPClient, path string, fields []formField, audio *audioFile) (io.ReadCloser, *OpenAIErr) {
+	defer audio.Close()
+	res, openaiErr := postMultipart(api, httpClient, path, multipartForm(fields, []formFile{audio.formFile()}))
+	if openaiErr != nil {
+		return nil, openaiErr
+	}
+	return res.Body, nil
+}
+
+// decodeAudioText reads the output of the transcriptions and translations APIs and closes it.
+func decodeAudioText(raw io.ReadCloser, responseFormat string) (*TranscriptionResponse, *OpenAIErr) {
+	if !isJSONTranscriptionFormat(responseFormat) {
+		b, err := io.ReadAll(raw)
+		if err != nil {
+			return nil, closeBody(raw, errCannotReadBody(err))
+		}
+		return &TranscriptionResponse{Text: string(b)}, closeBody(raw, nil)
 	}
-	return result, nil
+	return decodeResponse[TranscriptionResponse](&http.Response{Body: raw})
 }
@@ -0,0 +1,273 @@
+package openai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Defaults of LongTranscriptionOpts.
+const (
+	DefaultLongTranscriptionChunk       = 10 * time.Minute
+	DefaultLongTranscriptionOverlap     = 2 * time.Second
+	DefaultLongTranscriptionConcurrency = 4
+	DefaultSilenceThreshold             = 0.02
+	DefaultSilenceSearchWindow          = 30 * time.Second
+	DefaultMinSilence                   = 300 * time.Millisecond
+	silenceFrame                        = 20 * time.Millisecond
+)
+
+// LongTranscriptionOpts configures TranscribeLongAudio.
+type LongTranscriptionOpts struct {
+	Model, Language, Prompt string
+	Temperature             *float64
+	// WordTimestamps adds word timestamps to the merged transcript.
+	WordTimestamps bool
+	// MaxChunkDuration is the longest piece sent to the API. It is lowered when needed
+	// to keep every piece under MaxAudioFileSize.
+	MaxChunkDuration time.Duration
+	// Overlap between consecutive pieces cut at fixed windows. Words transcribed
+	// twice in the overlap are kept only once.
+	Overlap time.Duration
+	// SplitOnSilence cuts pieces, without overlap, in the middle of the last silence
+	// of at least MinSilence found in the SilenceSearchWindow before the end of each
+	// piece. Pieces without such a silence fall back to a fixed window with Overlap.
+	SplitOnSilence      bool
+	SilenceThreshold    float64 // RMS amplitude, between 0 and 1, under which audio is silent.
+	SilenceSearchWindow time.Duration
+	MinSilence          time.Duration
+	// Concurrency is the number of pieces transcribed at the same time. It requires
+	// NewHTTPClient: pieces sent with a single HTTPClient are sent one at a time.
+	Concurrency   int
+	NewHTTPClient HTTPClientFactory
+}
+
+func (opts *LongTranscriptionOpts) withDefaults() LongTranscriptionOpts {
+	o := *opts
+	if o.Model == "" {
+		o.Model = DefaultTranscriptionModel
+	}
+	if o.MaxChunkDuration <= 0 {
+		o.MaxChunkDuration = DefaultLongTranscriptionChunk
+	}
+	if o.Overlap < 0 {
+		o.Overlap = 0
+	} else if o.Overlap == 0 {
+		o.Overlap = DefaultLongTranscriptionOverlap
+	}
+	if o.SilenceThreshold <= 0 {
+		o.SilenceThreshold = DefaultSilenceThreshold
+	}
+	if o.SilenceSearchWindow <= 0 {
+		o.SilenceSearchWindow = DefaultSilenceSearchWindow
+	}
+	if o.MinSilence <= 0 {
+		o.MinSilence = DefaultMinSilence
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultLongTranscriptionConcurrency
+	}
+	o.Concurrency = o.NewHTTPClient.concurrency(o.Concurrency)
+	return o
+}
+
+// audioChunk is a piece of PCM audio given by its byte offsets.
+type audioChunk struct {
+	start, end int
+}
+
+// splitAudio cuts audio into pieces of at most opts.MaxChunkDuration.
+func splitAudio(audio *PCMAudio, opts LongTranscriptionOpts) ([]audioChunk, error) {
+	block := audio.BlockAlign()
+	maxBytes := audio.Bytes(opts.MaxChunkDuration)
+	if limit := (MaxAudioFileSize - wavHeaderSize) / block * block; maxBytes > limit {
+		maxBytes = limit
+	}
+	overlap := audio.Bytes(opts.Overlap)
+	if maxBytes <= 0 || overlap >= maxBytes {
+		return nil, fmt.Errorf("chunk duration %s must be longer than the overlap %s", opts.MaxChunkDuration, opts.Overlap)
+	}
+	var chunks []audioChunk
+	for start := 0; ; {
+		end := start + maxBytes
+		if end >= len(audio.Data) {
+			return append(chunks, audioChunk{start, len(audio.Data)}), nil
+		}
+		if opts.SplitOnSilence {
+			if cut, ok := findSilence(audio, start, end, opts); ok {
+				chunks = append(chunks, audioChunk{start, cut})
+				start = cut
+				continue
+			}
+		}
+		chunks = append(chunks, audioChunk{start, end})
+		start = end - overlap
+	}
+}
+
+// findSilence looks backwards from end for a silence of at least opts.MinSilence
+// and returns the byte offset of its middle.
+func findSilence(audio *PCMAudio, start, end int, opts LongTranscriptionOpts) (int, bool) {
+	frame := audio.Bytes(silenceFrame)
+	minFrames := int(opts.MinSilence / silenceFrame)
+	if frame == 0 {
+		return 0, false
+	}
+	searchStart := end - audio.Bytes(opts.SilenceSearchWindow)
+	if searchStart <= start {
+		searchStart = start + frame
+	}
+	silent := 0
+	for frameStart := end - frame; frameStart >= searchStart; frameStart -= frame {
+		if audio.rms(audio.Data[frameStart:frameStart+frame]) > opts.SilenceThreshold {
+			silent = 0
+			continue
+		}
+		silent++
+		if silent < minFrames {
+			continue
+		}
+		// Extend the silence backwards to cut in its middle.
+		runStart := frameStart
+		for runStart-frame >= searchStart && audio.rms(audio.Data[runStart-frame:runStart]) <= opts.SilenceThreshold {
+			runStart -= frame
+		}
+		runEnd := frameStart + silent*frame
+		block := audio.BlockAlign()
+		return (runStart + (runEnd-runStart)/2) / block * block, true
+	}
+	return 0, false
+}
+
+// TranscribeLongWAV reads a PCM WAV file and transcribes it with TranscribeLongAudio.
+func TranscribeLongWAV(api OpenAIClient, httpClient HTTPClient, r io.Reader, opts *LongTranscriptionOpts) (*TranscriptionResponse, *OpenAIErr) {
+	audio, err := ReadWAV(r)
+	if err != nil {
+		return nil, errInvalidAudio(err)
+	}
+	return TranscribeLongAudio(api, httpClient, audio, opts)
+}
+
+// TranscribeLongAudio transcribes audio of any length. The audio is split into
+// pieces under the API size limit, which are transcribed concurrently as
+// verbose_json and merged into one transcript whose timestamps refer to the
+// original audio.
+func TranscribeLongAudio(api OpenAIClient, httpClient HTTPClient, audio *PCMAudio, opts *LongTranscriptionOpts) (*TranscriptionResponse, *OpenAIErr) {
+	if opts == nil {
+		opts = &LongTranscriptionOpts{}
+	}
+	o := opts.withDefaults()
+	if err := audio.validate(); err != nil {
+		return nil, errInvalidAudio(err)
+	}
+	chunks, err := splitAudio(audio, o)
+	if err != nil {
+		return nil, errInvalidRequest(err)
+	}
+	granularities := []string{TimestampGranularitySegment}
+	if o.WordTimestamps {
+		granularities = append(granularities, TimestampGranularityWord)
+	}
+	results := make([]*TranscriptionResponse, len(chunks))
+	errs := parallel(len(chunks), o.Concurrency, o.NewHTTPClient, httpClient, func(httpClient HTTPClient, i int) error {
+		header := &bytes.Buffer{}
+		data := audio.Data[chunks[i].start:chunks[i].end]
+		if err := writeWAVHeader(header, audio.PCMFormat, uint32(len(data))); err != nil {
+			return err
+		}
+		res, err := Transcription(api, httpClient, &TranscriptionsRequestBody{
+			Model:                  o.Model,
+			Filename:               fmt.Sprintf("chunk-%d.wav", i),
+			Audio:                  io.MultiReader(header, bytes.NewReader(data)),
+			ContentType:            "audio/wav",
+			Language:               o.Language,
+			Prompt:                 o.Prompt,
+			Temperature:            o.Temperature,
+			ResponseFormat:         TranscriptionFormatVerboseJSON,
+			TimestampGranularities: granularities,
+		})
+		if err != nil {
+			return err
+		}
+		results[i] = res
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, asOpenAIErr(err, errCannotSendRequest)
+		}
+	}
+	merged := mergeTranscriptions(audio.PCMFormat, chunks, results)
+	merged.Duration = audio.Duration().Seconds()
+	return merged, nil
+}
+
+// mergeTranscriptions shifts the timestamps of each piece to the original timeline.
+// Where pieces overlap, segments and words are taken from the earlier piece before
+// the middle of the overlap and from the later piece after it.
+func mergeTranscriptions(format PCMFormat, chunks []audioChunk, results []*TranscriptionResponse) *TranscriptionResponse {
+	seconds := func(offset int) float64 { return format.Duration(offset).Seconds() }
+	boundary := func(i int) float64 {
+		if i < 0 {
+			return math.Inf(-1)
+		}
+		if i >= len(chunks)-1 {
+			return math.Inf(1)
+		}
+		end, next := seconds(chunks[i].end), seconds(chunks[i+1].start)
+		if next < end {
+			return (next + end) / 2
+		}
+		return next
+	}
+	merged := &TranscriptionResponse{Task: "transcribe"}
+	var text []string
+	for i, res := range results {
+		if merged.Language == "" {
+			merged.Language = res.Language
+		}
+		offset := seconds(chunks[i].start)
+		keepFrom, keepUntil := boundary(i-1), boundary(i)
+		for _, segment := range res.Segments {
+			segment.Start += offset
+			segment.End += offset
+			if middle := (segment.Start + segment.End) / 2; middle < keepFrom || middle >= keepUntil {
+				continue
+			}
+			segment.Id = len(merged.Segments)
+			merged.Segments = append(merged.Segments, segment)
+			if t := strings.TrimSpace(segment.Text); t != "" {
+				text = append(text, t)
+			}
+		}
+		if len(res.Segments) == 0 && strings.TrimSpace(res.Text) != "" {
+			text = append(text, strings.TrimSpace(res.Text))
+		}
+		for _, word := range res.Words {
+			word.Start += offset
+			word.End += offset
+			if word.Start < keepFrom || word.Start >= keepUntil {
+				continue
+			}
+			if n := len(merged.Words); n > 0 && isDuplicateWord(merged.Words[n-1], word) {
+				continue
+			}
+			merged.Words = append(merged.Words, word)
+		}
+	}
+	merged.Text = strings.Join(text, " ")
+	return merged
+}
+
+// isDuplicateWord reports whether next repeats last, as happens when a word
+// crossing the middle of an overlap is transcribed by both pieces.
+func isDuplicateWord(last, next TranscriptionWord) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.TrimFunc(s, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) }))
+	}
+	return normalize(last.Word) == normalize(next.Word) && next.Start < last.End+0.5
+}
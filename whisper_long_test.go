@@ -0,0 +1,200 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Simplou/goxios"
+)
+
+var testPCMFormat = PCMFormat{SampleRate: 1000, Channels: 1, BitsPerSample: 16}
+
+// testSpeech returns audio where second i has the constant amplitude levels[i],
+// so the mock below can tell which second of the original audio it received.
+func testSpeech(levels []int16) *PCMAudio {
+	data := make([]byte, 0, len(levels)*testPCMFormat.ByteRate())
+	for _, level := range levels {
+		for s := 0; s < testPCMFormat.SampleRate; s++ {
+			data = binary.LittleEndian.AppendUint16(data, uint16(level))
+		}
+	}
+	return &PCMAudio{PCMFormat: testPCMFormat, Data: data}
+}
+
+// MockLongWhisperHTTPClient transcribes every non-silent second of the uploaded
+// WAV as the word "w<n>", where n is the second of the original audio.
+type MockLongWhisperHTTPClient struct {
+	requests atomic.Int32
+}
+
+func (c *MockLongWhisperHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockLongWhisperHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	c.requests.Add(1)
+	var contentType string
+	for _, h := range opts.Headers {
+		if h.Key == "Content-Type" {
+			contentType = h.Value.(string)
+		}
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(opts.Body, params["boundary"])
+	var audio *PCMAudio
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			if audio, err = ReadWAV(part); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var segments []TranscriptionSegment
+	var words []TranscriptionWord
+	previous := int16(0)
+	for i := 0; i < len(audio.Data); i += 2 {
+		level := int16(binary.LittleEndian.Uint16(audio.Data[i:]))
+		if level != previous && level != 0 {
+			start := audio.PCMFormat.Duration(i).Seconds()
+			word := fmt.Sprintf("w%d", level/100-1)
+			words = append(words, TranscriptionWord{Word: word, Start: start, End: start + 0.9})
+			segments = append(segments, TranscriptionSegment{Start: start, End: start + 0.9, Text: " " + word})
+		}
+		previous = level
+	}
+	b, err := goxios.GenericJSON[any]{"language": "english", "segments": segments, "words": words}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(b))}, nil
+}
+
+func TestTranscribeLongAudioOverlap(t *testing.T) {
+	levels := make([]int16, 25)
+	expected := make([]string, len(levels))
+	for i := range levels {
+		levels[i] = int16(i+1) * 100
+		expected[i] = fmt.Sprintf("w%d", i)
+	}
+	wav := &bytes.Buffer{}
+	audio := testSpeech(levels)
+	if err := WriteWAV(wav, audio.PCMFormat, audio.Data); err != nil {
+		t.Fatal(err)
+	}
+	httpClient := new(MockLongWhisperHTTPClient)
+	res, err := TranscribeLongWAV(MockClient{"https://fake.api.openai.com/v1"}, httpClient, wav, &LongTranscriptionOpts{
+		MaxChunkDuration: 10 * time.Second,
+		Overlap:          3 * time.Second,
+		WordTimestamps:   true,
+		Concurrency:      2,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if n := httpClient.requests.Load(); n != 4 {
+		t.Errorf("Expected 4 pieces, got %d", n)
+	}
+	if res.Text != strings.Join(expected, " ") {
+		t.Errorf("Unexpected text: %q", res.Text)
+	}
+	if len(res.Words) != len(expected) {
+		t.Fatalf("Expected %d words, got %d: %+v", len(expected), len(res.Words), res.Words)
+	}
+	for i, word := range res.Words {
+		if word.Word != expected[i] || word.Start != float64(i) {
+			t.Errorf("Expected %s at %ds, got %s at %vs", expected[i], i, word.Word, word.Start)
+		}
+	}
+	for i, segment := range res.Segments {
+		if segment.Id != i || segment.Start != float64(i) {
+			t.Errorf("Unexpected segment %d: %+v", i, segment)
+		}
+	}
+	if res.Duration != 25 || res.Language != "english" {
+		t.Errorf("Unexpected duration %v or language %q", res.Duration, res.Language)
+	}
+}
+
+func TestSplitAudioOnSilence(t *testing.T) {
+	// The silence at 8s is cut rather than the longer one at 5s-7s, as it is the last.
+	levels := make([]int16, 20)
+	for i := range levels {
+		if i != 5 && i != 6 && i != 8 {
+			levels[i] = 1000
+		}
+	}
+	audio := testSpeech(levels)
+	opts := (&LongTranscriptionOpts{
+		MaxChunkDuration:    10 * time.Second,
+		SplitOnSilence:      true,
+		SilenceSearchWindow: 5 * time.Second,
+	}).withDefaults()
+	chunks, err := splitAudio(audio, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d time.Duration) int { return audio.Bytes(d) }
+	expected := []audioChunk{
+		{0, at(8500 * time.Millisecond)},
+		{at(8500 * time.Millisecond), at(18500 * time.Millisecond)},
+		{at(16500 * time.Millisecond), at(20 * time.Second)},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %v", len(expected), len(chunks), chunks)
+	}
+	for i := range chunks {
+		if chunks[i] != expected[i] {
+			t.Errorf("Chunk %d: expected %v, got %v", i, expected[i], chunks[i])
+		}
+	}
+}
+
+func TestTranscribeLongAudioSharedClient(t *testing.T) {
+	server := newConcurrencyServer(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, `{"language":"english","text":"w","segments":[{"start":0,"end":1,"text":" w"}]}`)
+	})
+	defer server.Close()
+	audio := testSpeech(make([]int16, 25))
+	api := MockClient{server.URL}
+	opts := &LongTranscriptionOpts{MaxChunkDuration: 5 * time.Second, Overlap: -1}
+	res, err := TranscribeLongAudio(api, goxios.New(context.Background()), audio, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(res.Segments) != 5 {
+		t.Errorf("Expected 5 segments, got %+v", res.Segments)
+	}
+	if n := server.maxInFlight.Load(); n != 1 {
+		t.Errorf("Expected the shared client to send one piece at a time, got %d", n)
+	}
+
+	server.maxInFlight.Store(0)
+	opts.NewHTTPClient = func() HTTPClient { return goxios.New(context.Background()) }
+	if _, err := TranscribeLongAudio(api, nil, audio, opts); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if n := server.maxInFlight.Load(); n > DefaultLongTranscriptionConcurrency {
+		t.Errorf("Expected at most %d parallel pieces, got %d", DefaultLongTranscriptionConcurrency, n)
+	}
+}
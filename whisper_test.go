@@ -1,9 +1,14 @@
 package openai
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/Simplou/goxios"
@@ -97,3 +102,206 @@ func TestTranscription(t *testing.T) {
 		})
 	}
 }
+
+// MockReadingWhisperHTTPClient reads the uploaded audio and echoes its size and content type.
+type MockReadingWhisperHTTPClient struct{}
+
+func (c *MockReadingWhisperHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockReadingWhisperHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	var contentType string
+	for _, h := range opts.Headers {
+		if h.Key == "Content-Type" {
+			contentType = h.Value.(string)
+		}
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(opts.Body, params["boundary"])
+	var text string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := io.Copy(io.Discard, part)
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			text = fmt.Sprintf("%d bytes of %s", n, part.Header.Get("Content-Type"))
+		}
+	}
+	b, err := goxios.JSON{"text": text}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(ioReader(b))}, nil
+}
+
+// zeroReader is an endless reader of unknown size.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestTranscriptionReader(t *testing.T) {
+	client := &headerClient{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	testCases := []struct {
+		name         string
+		body         *TranscriptionsRequestBody
+		expectedText string
+		expectedErr  bool
+	}{
+		{
+			name:         "Reader with content type",
+			body:         &TranscriptionsRequestBody{Filename: "upload.webm", ContentType: "audio/webm", Audio: strings.NewReader("audio")},
+			expectedText: "5 bytes of audio/webm",
+		},
+		{
+			name:         "Content type from extension",
+			body:         &TranscriptionsRequestBody{Filename: "upload.wav", Audio: io.LimitReader(zeroReader{}, 1024)},
+			expectedText: "1024 bytes of audio/wav",
+		},
+		{
+			name:        "Unsupported format",
+			body:        &TranscriptionsRequestBody{Filename: "upload.txt", Audio: strings.NewReader("audio")},
+			expectedErr: true,
+		},
+		{
+			name:        "Unsupported content type",
+			body:        &TranscriptionsRequestBody{Filename: "upload.mp3", ContentType: "text/plain", Audio: strings.NewReader("audio")},
+			expectedErr: true,
+		},
+		{
+			name:        "Known size over the limit",
+			body:        &TranscriptionsRequestBody{Filename: "upload.mp3", Audio: bytes.NewReader(make([]byte, MaxAudioFileSize+1))},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown size over the limit",
+			body:        &TranscriptionsRequestBody{Filename: "upload.mp3", Audio: io.LimitReader(zeroReader{}, MaxAudioFileSize+1)},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.body.Model = DefaultTranscriptionModel
+			resp, err := Transcription(client, new(MockReadingWhisperHTTPClient), tc.body)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, but got nil")
+				}
+				if err.Status() != http.StatusBadRequest {
+					t.Errorf("Expected status %d, got %d: %v", http.StatusBadRequest, err.Status(), err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if resp.Text != tc.expectedText {
+				t.Errorf("Expected %q, got %q", tc.expectedText, resp.Text)
+			}
+		})
+	}
+}
+
+// MockFormatWhisperHTTPClient answers with a fixed body, whatever the request.
+type MockFormatWhisperHTTPClient struct {
+	body string
+}
+
+func (c *MockFormatWhisperHTTPClient) Get(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	return &http.Response{}, nil
+}
+
+func (c *MockFormatWhisperHTTPClient) Post(url string, opts *goxios.RequestOpts) (*http.Response, error) {
+	io.Copy(io.Discard, opts.Body)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(c.body))}, nil
+}
+
+func TestTranscriptionResponseFormats(t *testing.T) {
+	client := &headerClient{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	srt := "1\n00:00:00,000 --> 00:00:01,000\nHello.\n"
+	verbose := `{"task":"transcribe","language":"english","duration":1.5,"text":"Hello.",` +
+		`"segments":[{"id":0,"start":0,"end":1.5,"text":"Hello.","avg_logprob":-0.2,"no_speech_prob":0.01}],` +
+		`"words":[{"word":"Hello","start":0.1,"end":0.9}]}`
+	temperature := 0.2
+
+	resp, err := Transcription(client, &MockFormatWhisperHTTPClient{srt}, &TranscriptionsRequestBody{
+		Filename:       "hello.mp3",
+		Audio:          strings.NewReader("audio"),
+		ResponseFormat: TranscriptionFormatSRT,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if resp.Text != srt {
+		t.Errorf("Expected raw srt output, got %q", resp.Text)
+	}
+
+	resp, err = Transcription(client, &MockFormatWhisperHTTPClient{verbose}, &TranscriptionsRequestBody{
+		Filename:               "hello.mp3",
+		Audio:                  strings.NewReader("audio"),
+		Language:               "en",
+		Temperature:            &temperature,
+		ResponseFormat:         TranscriptionFormatVerboseJSON,
+		TimestampGranularities: []string{TimestampGranularityWord, TimestampGranularitySegment},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(resp.Segments) != 1 || resp.Segments[0].End != 1.5 || resp.Segments[0].NoSpeechProb != 0.01 {
+		t.Errorf("Unexpected segments: %+v", resp.Segments)
+	}
+	if len(resp.Words) != 1 || resp.Words[0].Word != "Hello" {
+		t.Errorf("Unexpected words: %+v", resp.Words)
+	}
+
+	_, err = Transcription(client, &MockFormatWhisperHTTPClient{verbose}, &TranscriptionsRequestBody{
+		Filename:               "hello.mp3",
+		Audio:                  strings.NewReader("audio"),
+		TimestampGranularities: []string{TimestampGranularityWord},
+	})
+	if err == nil {
+		t.Error("Expected timestamp granularities without verbose_json to fail")
+	}
+}
+
+func TestTranslation(t *testing.T) {
+	client := &headerClient{MockClient: MockClient{baseUrl: "https://fake.api.openai.com/v1"}}
+	httpClient := new(MockImagesHTTPClient)
+	temperature := 0.0
+	_, err := Translation(client, httpClient, &TranslationsRequestBody{
+		Model:       DefaultTranscriptionModel,
+		Filename:    "voicemail.ogg",
+		Audio:       strings.NewReader("bonjour"),
+		Prompt:      "Customer support voicemail.",
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if httpClient.url != client.BaseURL()+"/audio/translations" {
+		t.Errorf("Unexpected url: %s", httpClient.url)
+	}
+	expected := map[string]string{"file": "bonjour", "model": DefaultTranscriptionModel, "prompt": "Customer support voicemail.", "temperature": "0"}
+	for field, value := range expected {
+		if httpClient.parts[field] != value {
+			t.Errorf("Expected %s %q, got %q", field, value, httpClient.parts[field])
+		}
+	}
+	if _, ok := httpClient.parts["language"]; ok {
+		t.Error("Translations must not send a language")
+	}
+}